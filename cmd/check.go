@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
+
+// Statuses of the checks, by exit code.
+var checkStatuses = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// Exit codes of the checks.
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+// Compute the status of a value with its warning and critical thresholds. When lowerIsWorse is true the thresholds are
+// crossed when the value is below them (e.g., an ASR), otherwise when the value is above them (e.g., a number of calls).
+func getCheckStatus(value float64, warning float64, critical float64, lowerIsWorse bool) int {
+	if lowerIsWorse {
+		value, warning, critical = -value, -warning, -critical
+	}
+	if value > critical {
+		return checkCritical
+	}
+	if value > warning {
+		return checkWarning
+	}
+	return checkOK
+}
+
+// Format a performance data of the plugin output: 'label'=value[unit];warning;critical;min;max.
+func formatPerfdata(label string, value string, unit string, warning string, critical string, min string, max string) string {
+	return strings.TrimRight(fmt.Sprintf("'%s'=%s%s;%s;%s;%s;%s", label, value, unit, warning, critical, min, max), ";")
+}
+
+// Print the plugin output of a check (status line and performance data) and exit with the status of the check.
+func exitCheck(name string, status int, message string, perfdata ...string) {
+	output := fmt.Sprintf("%s %s - %s", name, checkStatuses[status], message)
+	if len(perfdata) > 0 {
+		output += " | " + strings.Join(perfdata, " ")
+	}
+	fmt.Println(output)
+	os.Exit(status)
+}
+
+// Define the parent Cobra command of the monitoring checks.
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Run a monitoring check with a Nagios/Icinga compatible output.",
+	Long: `Run a monitoring check on the MOR database and print a Nagios/Icinga plugin output: a status line with the performance data, and the exit code 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN).
+
+Usage:
+  check [asr|concurrent-calls|blocked-countries|idle-dids] [flags]
+
+Example:
+  check asr -p 561 -m 15 -w 40 -c 30
+
+The SQL queries and the configuration messages are written to the error output, the standard output only contains the plugin output. A check exits with UNKNOWN when its flags are invalid, when the configuration file is not found or when the database request fails.`,
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	checkCmd.AddCommand(checkAsrCmd)
+	checkAsrCmd.Flags().StringP("provider", "p", "", "The ID of the provider")
+	checkAsrCmd.Flags().IntP("minutes", "m", 15, "The number of minutes of calls checked")
+	checkAsrCmd.Flags().Float64P("warning", "w", 40, "The ASR (%) under which the check is WARNING")
+	checkAsrCmd.Flags().Float64P("critical", "c", 30, "The ASR (%) under which the check is CRITICAL")
+	checkAsrCmd.Flags().IntP("attempts", "a", 10, "The minimum number of attempts to check the ASR")
+}
+
+// ModelMorCheckAsr represents the attempts and the answered calls of a provider.
+type ModelMorCheckAsr struct {
+	Provider string
+	Attempts int
+	Answered int
+}
+
+// Retrieve the attempts from the database and return them as a slice of models.
+func getModelMorCheckAsr(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCheckAsr
+
+		if err := rows.Scan(
+			&msg.Provider,
+			&msg.Attempts,
+			&msg.Answered,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Define the Cobra command for checking the ASR of a provider.
+var checkAsrCmd = &cobra.Command{
+	Use:   "asr",
+	Short: "Check the ASR of a provider over the last minutes.",
+	Long: `Check the answer seizure ratio (answered calls / attempts) of the calls sent to a provider during the last minutes.
+
+Usage:
+  check asr -p [provider] [-m [minutes]] [-w [warning]] [-c [critical]] [-a [attempts]]
+
+Flags:
+  -p, --provider string    The ID of the provider
+  -m, --minutes int        The number of minutes of calls checked (default 15)
+  -w, --warning float      The ASR (%) under which the check is WARNING (default 40)
+  -c, --critical float     The ASR (%) under which the check is CRITICAL (default 30)
+  -a, --attempts int       The minimum number of attempts to check the ASR (default 10)
+
+Example:
+  check asr -p 561 -m 30 -w 50 -c 35
+
+The check is OK without checking the ASR when the provider has less attempts than the minimum, to avoid alerts on a low traffic.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the check parameters from the command-line flags.
+		provider, _ := cmd.Flags().GetString("provider")
+		minutes, _ := cmd.Flags().GetInt("minutes")
+		warning, _ := cmd.Flags().GetFloat64("warning")
+		critical, _ := cmd.Flags().GetFloat64("critical")
+		minAttempts, _ := cmd.Flags().GetInt("attempts")
+
+		// Check the provider ID before using it in the SQL query.
+		if _, err := strconv.Atoi(provider); err != nil {
+			exitCheck("MOR ASR", checkUnknown, "Invalid provider ID: "+provider)
+		}
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			IFNULL(MAX(p.name), '') AS Provider,
+			COUNT(c.id) AS Attempts,
+			IFNULL(SUM(CASE WHEN c.disposition = 'ANSWERED' THEN 1 ELSE 0 END), 0) AS Answered
+		FROM mor.providers p
+		LEFT JOIN mor.calls c ON c.provider_id = p.id AND c.calldate > '%s'
+		WHERE p.id = %s;`, time.Now().Add(-time.Duration(minutes)*time.Minute).Format("2006-01-02 15:04:05"), provider)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCheckAsr)
+		if err != nil {
+			exitCheck("MOR ASR", checkUnknown, err.Error())
+		}
+		if len(results) == 0 {
+			exitCheck("MOR ASR", checkUnknown, "No result from the database")
+		}
+		if results[0].(ModelMorCheckAsr).Provider == "" {
+			exitCheck("MOR ASR", checkUnknown, "Unknown provider ID: "+provider)
+		}
+		result := results[0].(ModelMorCheckAsr)
+
+		// Compute the ASR and its status.
+		perfdataAttempts := formatPerfdata("attempts", strconv.Itoa(result.Attempts), "", "", "", "0", "")
+		if result.Attempts < minAttempts {
+			exitCheck("MOR ASR", checkOK, fmt.Sprintf("%s: %d attempts in the last %d minutes, less than %d attempts to check the ASR", result.Provider, result.Attempts, minutes, minAttempts), perfdataAttempts)
+		}
+		asr := float64(result.Answered) * 100 / float64(result.Attempts)
+		status := getCheckStatus(asr, warning, critical, true)
+
+		// Print the plugin output and exit with the status.
+		exitCheck("MOR ASR", status, fmt.Sprintf("%s: ASR %s%% (%d answered / %d attempts) in the last %d minutes", result.Provider, strconv.FormatFloat(asr, 'f', 2, 64), result.Answered, result.Attempts, minutes),
+			formatPerfdata("asr", strconv.FormatFloat(asr, 'f', 2, 64), "%", strconv.FormatFloat(warning, 'f', -1, 64)+":", strconv.FormatFloat(critical, 'f', -1, 64)+":", "0", "100"),
+			perfdataAttempts)
+	},
+}
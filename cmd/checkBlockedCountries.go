@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	checkCmd.AddCommand(checkBlockedCountriesCmd)
+	checkBlockedCountriesCmd.Flags().IntP("minutes", "m", 60, "The number of minutes of calls checked")
+	checkBlockedCountriesCmd.Flags().IntP("warning", "w", 0, "The number of calls to blocked countries over which the check is WARNING")
+	checkBlockedCountriesCmd.Flags().IntP("critical", "c", 5, "The number of calls to blocked countries over which the check is CRITICAL")
+}
+
+// ModelMorCheckBlockedCountries represents the calls to a destination.
+type ModelMorCheckBlockedCountries struct {
+	Destination string
+	Prefix      string
+	Calls       int
+	Answered    int
+}
+
+// Retrieve the calls by destination from the database and return them as a slice of models.
+func getModelMorCheckBlockedCountries(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCheckBlockedCountries
+
+		if err := rows.Scan(
+			&msg.Destination,
+			&msg.Prefix,
+			&msg.Calls,
+			&msg.Answered,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Define the Cobra command for checking the calls to the blocked countries.
+var checkBlockedCountriesCmd = &cobra.Command{
+	Use:   "blocked-countries",
+	Short: "Check the calls to the blocked countries over the last minutes.",
+	Long: `Check the calls made to the countries of the blockedCountries list during the last minutes.
+
+Usage:
+  check blocked-countries [-m [minutes]] [-w [warning]] [-c [critical]]
+
+Flags:
+  -m, --minutes int     The number of minutes of calls checked (default 60)
+  -w, --warning int     The number of calls to blocked countries over which the check is WARNING (default 0)
+  -c, --critical int    The number of calls to blocked countries over which the check is CRITICAL (default 5)
+
+Example:
+  check blocked-countries -m 15 -c 0
+
+The country of a call is found from its prefix like the other reports. The answered calls are the calls that went through the blocking, the status line lists the countries with their number of calls.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the check parameters from the command-line flags.
+		minutes, _ := cmd.Flags().GetInt("minutes")
+		warning, _ := cmd.Flags().GetInt("warning")
+		critical, _ := cmd.Flags().GetInt("critical")
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix,
+			COUNT(*) AS Calls,
+			SUM(CASE WHEN c.disposition = 'ANSWERED' THEN 1 ELSE 0 END) AS Answered
+		FROM mor.calls c
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.prefix <> ''
+		GROUP BY Destination, Prefix;`, time.Now().Add(-time.Duration(minutes)*time.Minute).Format("2006-01-02 15:04:05"))
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCheckBlockedCountries)
+		if err != nil {
+			exitCheck("MOR BLOCKED COUNTRIES", checkUnknown, err.Error())
+		}
+
+		// Count the calls to the blocked countries.
+		calls := 0
+		answered := 0
+		countries := make(map[string]int)
+		for _, result := range results {
+			oneResult := result.(ModelMorCheckBlockedCountries)
+			_, country := getCountryFromPrefix(oneResult.Prefix, oneResult.Destination)
+			if !slices.Contains(blockedCountries, country) {
+				continue
+			}
+			calls += oneResult.Calls
+			answered += oneResult.Answered
+			countries[country] += oneResult.Calls
+		}
+
+		// Compute the status of the calls to the blocked countries.
+		status := getCheckStatus(float64(calls), float64(warning), float64(critical), false)
+		message := fmt.Sprintf("%d calls (%d answered) to blocked countries in the last %d minutes", calls, answered, minutes)
+		if calls > 0 {
+			message += ": " + formatCountries(countries)
+		}
+
+		// Print the plugin output and exit with the status.
+		exitCheck("MOR BLOCKED COUNTRIES", status, message,
+			formatPerfdata("calls", strconv.Itoa(calls), "", strconv.Itoa(warning), strconv.Itoa(critical), "0", ""),
+			formatPerfdata("answered", strconv.Itoa(answered), "", "", "", "0", ""))
+	},
+}
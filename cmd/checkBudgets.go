@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(checkBudgetsCmd)
+	checkBudgetsCmd.Flags().StringP("date", "d", "", "The date of the check, the month to date is checked (default: now)")
+	checkBudgetsCmd.Flags().IntP("warning", "w", 80, "The percentage of a budget consumed or projected raising a warning")
+}
+
+// monthlyBudget represents the monthly budget of a device group or of a provider, in minutes and in cost.
+type monthlyBudget struct {
+	Minutes float64
+	Cost    float64
+}
+
+// ModelMorBudgetConsumption represents the month to date consumption of a device group with a provider.
+type ModelMorBudgetConsumption struct {
+	DeviceGroup string
+	ProviderID  string
+	Provider    string
+	Duration    int
+	Billsec     int
+	Price       float64
+}
+
+// Retrieve consumption data from the database and return it as a slice of models.
+func getModelMorBudgetConsumption(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorBudgetConsumption
+
+		if err := rows.Scan(
+			&msg.DeviceGroup,
+			&msg.ProviderID,
+			&msg.Provider,
+			&msg.Duration,
+			&msg.Billsec,
+			&msg.Price,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Compute the status of a consumption: CRITICAL when the budget is exceeded, WARNING when the consumption or the projection
+// reaches the warning percentage of the budget or when the projection exceeds the budget, OK otherwise.
+func getBudgetStatus(consumed float64, projected float64, budget float64, warning int) int {
+	if consumed >= budget {
+		return checkCritical
+	}
+	if consumed*100 >= budget*float64(warning) || projected >= budget {
+		return checkWarning
+	}
+	return checkOK
+}
+
+// Define the main Cobra command for checking the budgets.
+var checkBudgetsCmd = &cobra.Command{
+	Use:   "check-budgets",
+	Short: "Check the month to date minutes and cost of the device groups and providers against their monthly budgets.",
+	Long: `Compute the month to date minutes and provider cost of the answered calls by device group and by provider, project them to the end of the month, and check them against the monthly budgets defined in deviceGroupsBudgets and providersBudgets. The result is displayed in the terminal with an alert line per crossed threshold.
+
+Usage:
+  check-budgets [-d [date]] [-w [warning]]
+
+Flags:
+  -d, --date string    The date of the check, the month to date is checked (e.g., 'YYYY-MM-DD HH:mm:SS', default: now)
+  -w, --warning int    The percentage of a budget consumed or projected raising a warning (default 80)
+
+Example:
+  check-budgets -w 90
+
+The device groups consumption is the consumption of the prices report: the duration and the provider price of the calls sent to the providers of the providersID list from the devices of srcGroupDevicesID. The providers consumption is the billed seconds and the provider price of all their calls. The projection is the month to date consumption divided by the elapsed part of the month. A budget is CRITICAL when it is exceeded and WARNING when the consumption reaches the warning percentage or when the projection exceeds the budget. The command exits with 0 when all the budgets are OK, 1 with a warning and 2 with a critical budget, to be run from a monitoring cron.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the date and the warning percentage from the command-line flags.
+		dateStr, _ := cmd.Flags().GetString("date")
+		warning, _ := cmd.Flags().GetInt("warning")
+
+		// Check the month to date of now when no date is given.
+		if dateStr == "" {
+			dateStr = time.Now().Format("2006-01-02 15:04:05")
+		}
+
+		// Parse the provided date.
+		date, err := time.Parse("2006-01-02 15:04:05", dateStr)
+		if err != nil {
+			fmt.Println("Invalid date format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			os.Exit(3)
+		}
+
+		// Compute the month to date period and its elapsed part of the month.
+		monthStart := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		monthEnd := monthStart.AddDate(0, 1, 0)
+		elapsed := date.Sub(monthStart).Seconds() / monthEnd.Sub(monthStart).Seconds()
+
+		// Display the period information for the user's reference.
+		fmt.Println("check-budgets called with dateStart: " + monthStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + date.Format("2006-01-02 15:04:05"))
+
+		// Build the device group SQL filter.
+		srcDevicesIDFilter, _ := getDeviceGroupFilter("c.src_device_id")
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			%s AS DeviceGroup,
+			c.provider_id AS ProviderID,
+			p.name AS Provider,
+			SUM(c.duration) AS Duration,
+			SUM(c.billsec) AS Billsec,
+			IFNULL(SUM(c.provider_price), 0) AS Price
+		FROM mor.calls c
+		INNER JOIN mor.providers p ON c.provider_id = p.id
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.provider_id IN (%s) AND
+			c.disposition = 'ANSWERED'
+		GROUP BY DeviceGroup, ProviderID, Provider;`, srcDevicesIDFilter, monthStart.Format("2006-01-02 15:04:05"), date.Format("2006-01-02 15:04:05"), strings.Join(providersID, ","))
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorBudgetConsumption)
+		if err != nil {
+			log.Println(err)
+			os.Exit(3)
+		}
+
+		// Add the consumption to the providers, and to the device groups with the duration of the prices report.
+		minutes := make(map[string]float64)
+		costs := make(map[string]float64)
+		providersName := make(map[string]string)
+		for _, result := range results {
+			oneResult := result.(ModelMorBudgetConsumption)
+			providersName[oneResult.ProviderID] = oneResult.Provider
+			minutes["Provider;"+oneResult.ProviderID] += float64(oneResult.Billsec) / 60
+			costs["Provider;"+oneResult.ProviderID] += oneResult.Price
+			if oneResult.DeviceGroup != "" {
+				minutes["Device group;"+oneResult.DeviceGroup] += float64(oneResult.Duration) / 60
+				costs["Device group;"+oneResult.DeviceGroup] += oneResult.Price
+			}
+		}
+
+		// Build the list of the budgets to check.
+		var budgetsKeys []string
+		budgets := make(map[string]monthlyBudget)
+		for deviceGroup, budget := range deviceGroupsBudgets {
+			budgetsKeys = append(budgetsKeys, "Device group;"+deviceGroup)
+			budgets["Device group;"+deviceGroup] = budget
+		}
+		for providerID, budget := range providersBudgets {
+			budgetsKeys = append(budgetsKeys, "Provider;"+providerID)
+			budgets["Provider;"+providerID] = budget
+		}
+		sort.Strings(budgetsKeys)
+
+		// Check each budget and display the result.
+		exitCode := checkOK
+		var alerts []string
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "Type\tName\tMetric\tBudget\tMonth to date\tUsed (%)\tProjected\tProjected (%)\tStatus")
+		for _, key := range budgetsKeys {
+			budget := budgets[key]
+			keyParts := strings.SplitN(key, ";", 2)
+			name := keyParts[1]
+			if keyParts[0] == "Provider" && providersName[name] != "" {
+				name = providersName[name] + " (" + keyParts[1] + ")"
+			}
+
+			for _, metric := range []string{"Minutes", "Cost"} {
+				limit := budget.Minutes
+				consumed := minutes[key]
+				if metric == "Cost" {
+					limit = budget.Cost
+					consumed = costs[key]
+				}
+				if limit <= 0 {
+					continue
+				}
+				projected := consumed
+				if elapsed > 0 {
+					projected = consumed / elapsed
+				}
+
+				status := getBudgetStatus(consumed, projected, limit, warning)
+				if status > exitCode {
+					exitCode = status
+				}
+				usedPercent := strconv.FormatFloat(consumed*100/limit, 'f', 2, 64)
+				projectedPercent := strconv.FormatFloat(projected*100/limit, 'f', 2, 64)
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", keyParts[0], name, metric, formatPrice(limit), formatPrice(consumed), usedPercent, formatPrice(projected), projectedPercent, checkStatuses[status])
+				if status != checkOK {
+					alerts = append(alerts, fmt.Sprintf("%s: %s %s %s budget %s, month to date %s (%s%%), projected %s (%s%%)", checkStatuses[status], keyParts[0], name, metric, formatPrice(limit), formatPrice(consumed), usedPercent, formatPrice(projected), projectedPercent))
+				}
+			}
+		}
+		writer.Flush()
+
+		// Display the alerts and exit with the status of the worst budget.
+		for _, alert := range alerts {
+			fmt.Println(alert)
+		}
+		fmt.Printf("Budgets %s\n", checkStatuses[exitCode])
+		os.Exit(exitCode)
+	},
+}
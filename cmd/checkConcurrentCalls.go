@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	checkCmd.AddCommand(checkConcurrentCallsCmd)
+	checkConcurrentCallsCmd.Flags().StringP("provider", "p", "", "The ID of the provider (default: all the calls)")
+	checkConcurrentCallsCmd.Flags().IntP("warning", "w", 100, "The number of active calls over which the check is WARNING")
+	checkConcurrentCallsCmd.Flags().IntP("critical", "c", 150, "The number of active calls over which the check is CRITICAL")
+}
+
+// ModelMorCheckConcurrentCalls represents the active calls.
+type ModelMorCheckConcurrentCalls struct {
+	Calls    int
+	Answered int
+}
+
+// Retrieve the active calls from the database and return them as a slice of models.
+func getModelMorCheckConcurrentCalls(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCheckConcurrentCalls
+
+		if err := rows.Scan(
+			&msg.Calls,
+			&msg.Answered,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Define the Cobra command for checking the active concurrent calls.
+var checkConcurrentCallsCmd = &cobra.Command{
+	Use:   "concurrent-calls",
+	Short: "Check the number of active concurrent calls.",
+	Long: `Check the number of active concurrent calls of the MOR active calls, in total or of a provider.
+
+Usage:
+  check concurrent-calls [-p [provider]] [-w [warning]] [-c [critical]]
+
+Flags:
+  -p, --provider string    The ID of the provider (default: all the calls)
+  -w, --warning int        The number of active calls over which the check is WARNING (default 100)
+  -c, --critical int       The number of active calls over which the check is CRITICAL (default 150)
+
+Example:
+  check concurrent-calls -p 561 -w 25 -c 30
+
+Set the thresholds under the channels of the provider trunk (see the morCallsErlangBChannelsByProvidersByDeviceGroups command) to be alerted before the calls are rejected.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the check parameters from the command-line flags.
+		provider, _ := cmd.Flags().GetString("provider")
+		warning, _ := cmd.Flags().GetInt("warning")
+		critical, _ := cmd.Flags().GetInt("critical")
+
+		// Check the provider ID before using it in the SQL query.
+		providerFilter := ""
+		if provider != "" {
+			if _, err := strconv.Atoi(provider); err != nil {
+				exitCheck("MOR CONCURRENT CALLS", checkUnknown, "Invalid provider ID: "+provider)
+			}
+			providerFilter = "WHERE a.provider_id = " + provider
+		}
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			COUNT(*) AS Calls,
+			IFNULL(SUM(CASE WHEN a.answer_time IS NOT NULL THEN 1 ELSE 0 END), 0) AS Answered
+		FROM mor.activecalls a
+		%s;`, providerFilter)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCheckConcurrentCalls)
+		if err != nil {
+			exitCheck("MOR CONCURRENT CALLS", checkUnknown, err.Error())
+		}
+		if len(results) == 0 {
+			exitCheck("MOR CONCURRENT CALLS", checkUnknown, "No result from the database")
+		}
+		result := results[0].(ModelMorCheckConcurrentCalls)
+
+		// Compute the status of the active calls.
+		status := getCheckStatus(float64(result.Calls), float64(warning), float64(critical), false)
+		target := "all providers"
+		if provider != "" {
+			target = "provider " + provider
+		}
+
+		// Print the plugin output and exit with the status.
+		exitCheck("MOR CONCURRENT CALLS", status, fmt.Sprintf("%d active calls (%d answered) on %s", result.Calls, result.Answered, target),
+			formatPerfdata("calls", strconv.Itoa(result.Calls), "", strconv.Itoa(warning), strconv.Itoa(critical), "0", ""),
+			formatPerfdata("answered", strconv.Itoa(result.Answered), "", "", "", "0", ""))
+	},
+}
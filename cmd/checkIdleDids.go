@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	checkCmd.AddCommand(checkIdleDidsCmd)
+	checkIdleDidsCmd.Flags().IntP("days", "d", 30, "The number of days without incoming or outgoing call of an idle DID")
+	checkIdleDidsCmd.Flags().IntP("warning", "w", 10, "The number of idle DIDs over which the check is WARNING")
+	checkIdleDidsCmd.Flags().IntP("critical", "c", 50, "The number of idle DIDs over which the check is CRITICAL")
+}
+
+// ModelMorCheckIdleDids represents an active DID and whether it is idle.
+type ModelMorCheckIdleDids struct {
+	Did  string
+	Idle bool
+}
+
+// Retrieve the active DIDs from the database and return them as a slice of models.
+func getModelMorCheckIdleDids(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCheckIdleDids
+
+		if err := rows.Scan(
+			&msg.Did,
+			&msg.Idle,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Define the Cobra command for checking the idle DIDs.
+var checkIdleDidsCmd = &cobra.Command{
+	Use:   "idle-dids",
+	Short: "Check the number of active DIDs without incoming or outgoing call during the last days.",
+	Long: `Check the number of active DIDs without any incoming or outgoing call during the last days, with the same definition of a used DID as the morUnusedDids command: the calls received on the DID and the calls sent to a provider with the DID as caller ID.
+
+Usage:
+  check idle-dids [-d [days]] [-w [warning]] [-c [critical]]
+
+Flags:
+  -d, --days int        The number of days without incoming or outgoing call of an idle DID (default 30)
+  -w, --warning int     The number of idle DIDs over which the check is WARNING (default 10)
+  -c, --critical int    The number of idle DIDs over which the check is CRITICAL (default 50)
+
+Example:
+  check idle-dids -d 7 -w 0 -c 5
+
+The status line lists the first idle DIDs, use the morUnusedDids command to export all of them with their provider, owner, device and last call.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the check parameters from the command-line flags.
+		days, _ := cmd.Flags().GetInt("days")
+		warning, _ := cmd.Flags().GetInt("warning")
+		critical, _ := cmd.Flags().GetInt("critical")
+
+		// Construct the SQL query with placeholders.
+		dateStartStr := time.Now().AddDate(0, 0, -days).Format("2006-01-02 15:04:05")
+		request := fmt.Sprintf(`SELECT
+			d.did AS Did,
+			IFNULL(incoming.Calls, 0) + IFNULL(outgoing.Calls, 0) = 0 AS Idle
+		FROM mor.dids d
+		%s
+		WHERE d.status = 'active'
+		ORDER BY d.did;`, getDidsTrafficJoin(dateStartStr, dateStartStr))
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCheckIdleDids)
+		if err != nil {
+			exitCheck("MOR IDLE DIDS", checkUnknown, err.Error())
+		}
+		if len(results) == 0 {
+			exitCheck("MOR IDLE DIDS", checkUnknown, "No active DID found in the database")
+		}
+
+		// List the idle DIDs.
+		var idleDids []string
+		for _, result := range results {
+			oneResult := result.(ModelMorCheckIdleDids)
+			if oneResult.Idle {
+				idleDids = append(idleDids, oneResult.Did)
+			}
+		}
+
+		// Compute the status of the idle DIDs.
+		status := getCheckStatus(float64(len(idleDids)), float64(warning), float64(critical), false)
+		message := fmt.Sprintf("%d of %d active DIDs without incoming or outgoing call in the last %d days", len(idleDids), len(results), days)
+		if len(idleDids) > 10 {
+			message += ": " + strings.Join(idleDids[:10], ", ") + "..."
+		} else if len(idleDids) > 0 {
+			message += ": " + strings.Join(idleDids, ", ")
+		}
+
+		// Print the plugin output and exit with the status.
+		exitCheck("MOR IDLE DIDS", status, message,
+			formatPerfdata("idle", strconv.Itoa(len(idleDids)), "", strconv.Itoa(warning), strconv.Itoa(critical), "0", strconv.Itoa(len(results))),
+			formatPerfdata("active", strconv.Itoa(len(results)), "", "", "", "0", ""))
+	},
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import "testing"
+
+func TestGetCheckStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        float64
+		warning      float64
+		critical     float64
+		lowerIsWorse bool
+		want         int
+	}{
+		{"higher is worse, below the warning", 5, 10, 20, false, checkOK},
+		{"higher is worse, on the warning", 10, 10, 20, false, checkOK},
+		{"higher is worse, over the warning", 11, 10, 20, false, checkWarning},
+		{"higher is worse, on the critical", 20, 10, 20, false, checkWarning},
+		{"higher is worse, over the critical", 21, 10, 20, false, checkCritical},
+		{"lower is worse, over the warning", 50, 40, 20, true, checkOK},
+		{"lower is worse, on the warning", 40, 40, 20, true, checkOK},
+		{"lower is worse, below the warning", 39, 40, 20, true, checkWarning},
+		{"lower is worse, on the critical", 20, 40, 20, true, checkWarning},
+		{"lower is worse, below the critical", 19, 40, 20, true, checkCritical},
+		{"zero thresholds", 1, 0, 0, false, checkCritical},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := getCheckStatus(test.value, test.warning, test.critical, test.lowerIsWorse); got != test.want {
+				t.Errorf("getCheckStatus(%g, %g, %g, %v) = %s, want %s", test.value, test.warning, test.critical, test.lowerIsWorse, checkStatuses[got], checkStatuses[test.want])
+			}
+		})
+	}
+}
+
+func TestFormatPerfdata(t *testing.T) {
+	tests := []struct {
+		name                                            string
+		label, value, unit, warning, critical, min, max string
+		want                                            string
+	}{
+		{"all fields", "asr", "45.50", "%", "40", "20", "0", "100", "'asr'=45.50%;40;20;0;100"},
+		{"without maximum", "calls", "12", "", "50", "100", "0", "", "'calls'=12;50;100;0"},
+		{"value only", "dids", "3", "", "", "", "", "", "'dids'=3"},
+		{"label with spaces", "EN minutes", "120", "", "", "", "0", "", "'EN minutes'=120;;;0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := formatPerfdata(test.label, test.value, test.unit, test.warning, test.critical, test.min, test.max); got != test.want {
+				t.Errorf("formatPerfdata = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
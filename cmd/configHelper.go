@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Define mapping of device groups to source devices.
+// Change it to match your MOR database devices ID and to choose the name of your device groups.
+var srcGroupDevicesID = map[string][]string{
+	"EN": {"181", "1081"},
+	"FR": {"671", "1072"},
+}
+
+// List of provider IDs.
+// Change it to match your MOR database providers ID.
+var providersID = []string{"561", "721", "21", "31", "101", "111", "441", "711", "781", "801"}
+
+// Define the monthly rental cost of the DIDs by provider ID, e.g. {"561": 1.50}.
+// MOR only stores per-minute DID rates, not the monthly rental billed by the DID providers: add the rental cost of each of your DIDs
+// providers, the DIDs of the other providers have an empty monthly cost in the unused DIDs report.
+var didsMonthlyCostByProvider = map[string]float64{}
+
+// List of the high-risk destination prefixes (international premium rate, satellite and frequent toll fraud destinations).
+// Change it to match the destinations you consider at risk.
+var highRiskPrefixes = []string{"870", "881", "882", "883", "979", "53", "216", "220", "224", "231", "232", "247", "252", "370", "371", "675", "677", "678", "679", "686", "688", "689", "690", "3389"}
+
+// Define the business hours (from businessHoursStart included to businessHoursEnd excluded, from Monday to Friday).
+// Change it to match your working hours, the calls outside these hours are considered out-of-hours.
+var businessHoursStart = 8
+var businessHoursEnd = 19
+
+// Build the SQL CASE expression returning the device group of the given device column, and the list of all the grouped devices.
+// The devices outside of the groups have an empty device group.
+func getDeviceGroupFilter(deviceColumn string) (string, string) {
+	// Initialize variables to store SQL filters and device IDs.
+	srcDevicesIDFilter := ""
+	var srcDevicesIDList []string
+
+	// Build SQL filters based on the mapping of device groups to source devices.
+	for oneSrcGroup, oneSrcGroupDevicesID := range srcGroupDevicesID {
+		srcDevicesIDFilter += fmt.Sprintf("			WHEN %s IN (%s) THEN '%s' \n", deviceColumn, strings.Join(oneSrcGroupDevicesID, ","), oneSrcGroup)
+		srcDevicesIDList = append(srcDevicesIDList, oneSrcGroupDevicesID...)
+	}
+
+	return fmt.Sprintf("CASE\n%s		ELSE '' END", srcDevicesIDFilter), strings.Join(srcDevicesIDList, ",")
+}
+
+// Define the monthly budgets of the device groups (by device group name) and of the providers (by provider ID), for the check-budgets command.
+// Change it to match your budgets: a budget can limit the minutes, the cost or both, a zero value is not checked.
+var deviceGroupsBudgets = map[string]monthlyBudget{
+	"EN": {Minutes: 50000, Cost: 500.00},
+	"FR": {Minutes: 50000, Cost: 500.00},
+}
+var providersBudgets = map[string]monthlyBudget{
+	"561": {Cost: 1000.00},
+	"721": {Minutes: 100000},
+}
+
+// List of the countries where the calls are blocked, by country name, for the check blocked-countries command.
+// Change it to match the countries blocked on your platform: a call to one of these countries is a routing or a fraud issue.
+var blockedCountries = []string{"Cuba", "Somalia", "Guinea", "Liberia", "Sierra Leone", "Papua New Guinea", "Solomon Islands", "Vanuatu", "Fiji"}
@@ -0,0 +1,384 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.Flags().StringP("file", "f", "schedule.csv", "The schedule file of the jobs")
+	daemonCmd.Flags().StringP("state", "s", "daemon_state.json", "The file of the last run state of the jobs")
+	daemonCmd.Flags().StringP("log", "l", "daemon_log.csv", "The file of the outcomes of the jobs")
+	daemonCmd.Flags().IntP("retries", "r", 2, "The number of retries of a failed job")
+	daemonCmd.Flags().IntP("retryDelay", "d", 60, "The number of seconds between two tries of a job")
+	daemonCmd.Flags().IntP("timeout", "t", 3600, "The maximum number of seconds of a try of a job")
+}
+
+// Commands which cannot be scheduled.
+var daemonExcludedCommands = []string{"daemon", "serve", "serve-metrics", "help", "completion"}
+
+// Commands displaying their result in the terminal, the other commands fail when they export no file.
+var daemonTerminalCommands = []string{"check", "check-budgets", "lookup"}
+
+// scheduledJob represents a job of the schedule file.
+type scheduledJob struct {
+	name      string
+	schedule  *cronSchedule
+	report    string
+	period    string
+	output    string
+	arguments []string
+}
+
+// jobState represents the last run of a job, persisted in the state file.
+type jobState struct {
+	LastScheduled time.Time `json:"lastScheduled"`
+	LastRun       time.Time `json:"lastRun"`
+	LastStatus    string    `json:"lastStatus"`
+	LastError     string    `json:"lastError,omitempty"`
+}
+
+// jobsDaemon runs the scheduled jobs and keeps their state.
+type jobsDaemon struct {
+	jobs       []scheduledJob
+	executable string
+	configFile string
+	stateFile  string
+	logFile    string
+	retries    int
+	retryDelay time.Duration
+	timeout    time.Duration
+	mutex      sync.Mutex
+	states     map[string]jobState
+	running    map[string]bool
+}
+
+// Read the jobs of the schedule file: Name;Schedule;Report;Period;Output;Arguments.
+func readScheduleFile(filename string) ([]scheduledJob, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	// Skip the header row.
+	if _, err := reader.Read(); err != nil {
+		return nil, err
+	}
+
+	// Check each job of the file.
+	var jobs []scheduledJob
+	names := make(map[string]bool)
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+		if len(record) < 5 {
+			return nil, fmt.Errorf("line %d of %s: the columns Name, Schedule, Report, Period and Output are required", line, filename)
+		}
+
+		job := scheduledJob{name: strings.TrimSpace(record[0]), report: strings.TrimSpace(record[2]), period: strings.TrimSpace(record[3]), output: strings.TrimSpace(record[4])}
+		if len(record) > 5 {
+			job.arguments = strings.Fields(record[5])
+		}
+		if job.name == "" || names[job.name] {
+			return nil, fmt.Errorf("line %d of %s: empty or duplicate job name %q", line, filename, job.name)
+		}
+		names[job.name] = true
+		if job.schedule, err = parseCronSchedule(record[1]); err != nil {
+			return nil, fmt.Errorf("line %d of %s: %v", line, filename, err)
+		}
+		command := findSchedulableCommand(job.report)
+		if command == nil {
+			return nil, fmt.Errorf("line %d of %s: unknown report %q", line, filename, job.report)
+		}
+		if job.period != "" && job.period != "none" {
+			if _, _, err := getRelativePeriod(job.period, time.Now()); err != nil {
+				return nil, fmt.Errorf("line %d of %s: %v", line, filename, err)
+			}
+			if command.Flags().Lookup("dateStart") == nil || command.Flags().Lookup("dateEnd") == nil {
+				return nil, fmt.Errorf("line %d of %s: the report %s has no dateStart and dateEnd options, use the period none", line, filename, job.report)
+			}
+		}
+		if job.output == "" {
+			job.output = "."
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// Find a command which can be scheduled by its name, nil when it does not exist or cannot be scheduled.
+func findSchedulableCommand(name string) *cobra.Command {
+	for _, excludedCommand := range daemonExcludedCommands {
+		if name == excludedCommand {
+			return nil
+		}
+	}
+	for _, command := range rootCmd.Commands() {
+		if command.Name() == name {
+			return command
+		}
+	}
+	return nil
+}
+
+// Read the state file, an empty state when it does not exist yet.
+func (daemon *jobsDaemon) loadState() error {
+	daemon.states = make(map[string]jobState)
+	content, err := os.ReadFile(daemon.stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, &daemon.states)
+}
+
+// Write the state file, through a temporary file so that it is never partially written.
+func (daemon *jobsDaemon) saveState() error {
+	content, err := json.MarshalIndent(daemon.states, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(daemon.stateFile+".tmp", content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(daemon.stateFile+".tmp", daemon.stateFile)
+}
+
+// Append an outcome of a job to the log file.
+func (daemon *jobsDaemon) logOutcome(job scheduledJob, scheduled time.Time, status string, tries int, duration time.Duration, details string) {
+	log.Printf("job %s scheduled at %s: %s after %d tries (%s)", job.name, scheduled.Format("2006-01-02 15:04:05"), status, tries, details)
+
+	// Create the log file with its header row.
+	_, err := os.Stat(daemon.logFile)
+	newFile := os.IsNotExist(err)
+	logFile, err := os.OpenFile(daemon.logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer logFile.Close()
+	if newFile {
+		fmt.Fprintln(logFile, "Time;Job;Report;Scheduled;Status;Tries;Duration (seconds);Details")
+	}
+	fmt.Fprintf(logFile, "%s;%s;%s;%s;%s;%d;%d;%s\n", time.Now().Format("2006-01-02 15:04:05"), job.name, job.report, scheduled.Format("2006-01-02 15:04:05"), status, tries, int(duration.Seconds()), strings.ReplaceAll(details, ";", ","))
+}
+
+// Run a job scheduled at a time, unless its previous run is still running.
+func (daemon *jobsDaemon) runJob(job scheduledJob, scheduled time.Time) {
+	daemon.mutex.Lock()
+	if daemon.running[job.name] {
+		daemon.mutex.Unlock()
+		daemon.logOutcome(job, scheduled, "SKIPPED", 0, 0, "previous run still running")
+		return
+	}
+	daemon.running[job.name] = true
+	daemon.mutex.Unlock()
+
+	// Build the arguments of the report with the period computed at the scheduled time.
+	args := []string{job.report, "--config", daemon.configFile}
+	if job.period != "" && job.period != "none" {
+		dateStart, dateEnd, _ := getRelativePeriod(job.period, scheduled)
+		args = append(args, "--dateStart="+dateStart.Format("2006-01-02 15:04:05"), "--dateEnd="+dateEnd.Format("2006-01-02 15:04:05"))
+	}
+	args = append(args, job.arguments...)
+
+	// Try the job until it succeeds or the retries are exhausted.
+	start := time.Now()
+	status := "FAILED"
+	details := ""
+	tries := 0
+	for tries <= daemon.retries {
+		if tries > 0 {
+			time.Sleep(daemon.retryDelay)
+		}
+		tries++
+		files, err := daemon.runReport(job, args)
+		if err == nil {
+			status = "OK"
+			details = strings.Join(files, " ")
+			break
+		}
+		details = err.Error()
+	}
+	daemon.logOutcome(job, scheduled, status, tries, time.Since(start), details)
+
+	// Persist the state of the job.
+	daemon.mutex.Lock()
+	defer daemon.mutex.Unlock()
+	daemon.running[job.name] = false
+	state := jobState{LastScheduled: scheduled, LastRun: start, LastStatus: status}
+	if status != "OK" {
+		state.LastError = details
+	}
+	daemon.states[job.name] = state
+	if err := daemon.saveState(); err != nil {
+		log.Println(err)
+	}
+}
+
+// Run a report in its own process and working directory, and move its exports to the output directory of the job.
+func (daemon *jobsDaemon) runReport(job scheduledJob, args []string) ([]string, error) {
+	if err := os.MkdirAll(job.output, 0755); err != nil {
+		return nil, err
+	}
+	workDir, err := os.MkdirTemp(job.output, ".job-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemon.timeout)
+	defer cancel()
+	reportCmd := exec.CommandContext(ctx, daemon.executable, args...)
+	reportCmd.Dir = workDir
+	output, err := reportCmd.Output()
+	if err != nil {
+		// The reports log their fatal error as the last line of the error output, after the configuration file line,
+		// the checks print their status on the standard output.
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			lines := strings.Split(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+			if len(lines) < 2 {
+				lines = strings.Split(strings.TrimSpace(string(output)), "\n")
+			}
+			return nil, fmt.Errorf("%v: %s", err, lines[len(lines)-1])
+		}
+		return nil, err
+	}
+
+	// Move the exports prefixed with the job name. The reports print their invalid options and exit without an export.
+	files, _ := filepath.Glob(filepath.Join(workDir, "*.csv"))
+	if len(files) == 0 && !slices.Contains(daemonTerminalCommands, job.report) {
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		return nil, fmt.Errorf("no export file: %s", lines[len(lines)-1])
+	}
+	var exported []string
+	for _, file := range files {
+		destination := filepath.Join(job.output, sanitizeFilename(job.name)+"_"+filepath.Base(file))
+		if err := os.Rename(file, destination); err != nil {
+			return exported, err
+		}
+		exported = append(exported, destination)
+	}
+
+	return exported, nil
+}
+
+// Define the main Cobra command for running the scheduled jobs.
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the reports of a schedule file at their cron schedule.",
+	Long: `Read a schedule file of jobs and run each job at its cron schedule: a report or a command with a period relative to the scheduled time, its exports being saved in an output directory. The outcomes of the jobs are logged in a log file and their last run is persisted in a state file.
+
+Usage:
+  daemon [-f [file]] [-s [state]] [-l [log]] [-r [retries]] [-d [retryDelay]] [-t [timeout]]
+
+Flags:
+  -f, --file string       The schedule file of the jobs (default "schedule.csv")
+  -s, --state string      The file of the last run state of the jobs (default "daemon_state.json")
+  -l, --log string        The file of the outcomes of the jobs (default "daemon_log.csv")
+  -r, --retries int       The number of retries of a failed job (default 2)
+  -d, --retryDelay int    The number of seconds between two tries of a job (default 60)
+  -t, --timeout int       The maximum number of seconds of a try of a job (default 3600)
+
+Example:
+  daemon -f schedule.csv -r 3
+
+The schedule file is separated by semicolons with a header row and the columns Name, Schedule (cron expression of 5 fields or @hourly, @daily, @weekly, @monthly, @yearly), Report (the command name), Period (today, yesterday, last-week, this-month, last-month, last-N-days or none, required for the reports without dateStart and dateEnd options), Output (the directory of the exports) and Arguments (the other flags of the command). A job is skipped when its previous run is still running, and a failed try (non-zero exit code, or no export file for the commands other than check, check-budgets and lookup, e.g. an invalid option) is retried. When the daemon restarts, the last missed run of each job since its last scheduled run is run once. Each job is run in its own process with the same configuration file, its exports are prefixed with the job name.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the daemon parameters from the command-line flags.
+		file, _ := cmd.Flags().GetString("file")
+		stateFile, _ := cmd.Flags().GetString("state")
+		logFile, _ := cmd.Flags().GetString("log")
+		retries, _ := cmd.Flags().GetInt("retries")
+		retryDelay, _ := cmd.Flags().GetInt("retryDelay")
+		timeout, _ := cmd.Flags().GetInt("timeout")
+
+		// Read and check the schedule file.
+		jobs, err := readScheduleFile(file)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		// Retrieve the executable and the configuration file to run the jobs.
+		executable, err := os.Executable()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if viper.ConfigFileUsed() == "" {
+			fmt.Println("No configuration file loaded. Please use the --config option to run the jobs with a configuration file")
+			os.Exit(errorExitCode)
+		}
+		configFile, err := filepath.Abs(viper.ConfigFileUsed())
+		if err != nil {
+			log.Fatal(err)
+		}
+		daemon := &jobsDaemon{jobs: jobs, executable: executable, configFile: configFile, stateFile: stateFile, logFile: logFile, retries: retries, retryDelay: time.Duration(retryDelay) * time.Second, timeout: time.Duration(timeout) * time.Second, running: make(map[string]bool)}
+		if err := daemon.loadState(); err != nil {
+			log.Fatal(err)
+		}
+
+		// Display the daemon information for the user's reference.
+		fmt.Printf("daemon called with file: %s (%d jobs), state: %s and log: %s\n", file, len(jobs), stateFile, logFile)
+
+		// Run once the last missed run of the jobs since their last scheduled run.
+		now := time.Now()
+		for _, job := range jobs {
+			state, found := daemon.states[job.name]
+			if !found {
+				continue
+			}
+			if missed := job.schedule.lastBetween(state.LastScheduled, now); !missed.IsZero() {
+				go daemon.runJob(job, missed)
+			}
+		}
+
+		// Check the schedule of the jobs at each minute, the minutes missed by a late wake up are checked too.
+		lastChecked := now.Truncate(time.Minute)
+		for {
+			time.Sleep(time.Until(lastChecked.Add(time.Minute)))
+			for !lastChecked.Add(time.Minute).After(time.Now()) {
+				lastChecked = lastChecked.Add(time.Minute)
+				for _, job := range jobs {
+					if job.schedule.matches(lastChecked) {
+						go daemon.runJob(job, lastChecked)
+					}
+				}
+			}
+		}
+	},
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadScheduleFilePeriod(t *testing.T) {
+	tests := []struct {
+		name    string
+		job     string
+		wantErr bool
+	}{
+		{"period of a report with dates", "prices;@daily;morCallsPricesByDestinationsByDeviceGroupsByProviders;yesterday;;", false},
+		{"no period of a report without dates", "dids;@daily;morUnusedDids;none;;-d 90", false},
+		{"empty period of a report without dates", "dids;@daily;morUnusedDids;;;", false},
+		{"period of a report without dates", "dids;@daily;morUnusedDids;last-month;;", true},
+		{"invalid period", "prices;@daily;morCallsPricesByDestinationsByDeviceGroupsByProviders;last-year;;", true},
+		{"unknown report", "unknown;@daily;unknownReport;none;;", true},
+		{"excluded report", "daemon;@daily;daemon;none;;", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "schedule.csv")
+			if err := os.WriteFile(filename, []byte("Name;Schedule;Report;Period;Output;Arguments\n"+test.job+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := readScheduleFile(filename)
+			if (err != nil) != test.wantErr {
+				t.Errorf("readScheduleFile returned %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunReportExports(t *testing.T) {
+	tests := []struct {
+		name      string
+		report    string
+		script    string
+		wantFiles int
+		wantErr   bool
+	}{
+		{"report with an export", "morUnusedDids", "echo exported > 2023_01_01_00_00_00_export.csv", 1, false},
+		{"report without an export", "morUnusedDids", "echo Invalid days", 0, true},
+		{"failed report", "morUnusedDids", "echo failed >&2; exit 1", 0, true},
+		{"terminal command", "check-budgets", "echo Budgets OK", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			daemon := &jobsDaemon{executable: "/bin/sh", timeout: time.Minute}
+			job := scheduledJob{name: "job", report: test.report, output: t.TempDir()}
+			files, err := daemon.runReport(job, []string{"-c", test.script})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("runReport error = %v, want error %v", err, test.wantErr)
+			}
+			if len(files) != test.wantFiles {
+				t.Errorf("runReport exported %v, want %d files", files, test.wantFiles)
+			}
+		})
+	}
+}
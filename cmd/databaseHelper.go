@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"net"
 	"os"
 
@@ -31,27 +30,42 @@ type Db struct {
 }
 
 // newDb creates a new database connection using the given connection string.
-func newDb(dbConnectString string) *Db {
+func newDb(dbConnectString string) (*Db, error) {
 	db, err := sql.Open("mysql", dbConnectString)
 	if err != nil {
-		log.Fatalf("Unable to connect DB: %v", err)
+		return nil, fmt.Errorf("unable to connect DB: %v", err)
 	}
 
-	return &Db{db: db}
+	return &Db{db: db}, nil
 }
 
 // prepare compiles a SQL query and returns a prepared statement.
-func (db *Db) prepare(query string) *sql.Stmt {
-	stmt, err := db.db.Prepare(query)
+func (db *Db) prepare(query string) (*sql.Stmt, error) {
+	return db.db.Prepare(query)
+}
+
+// request prepares a SQL query and returns its results using the provided function.
+func (db *Db) request(request string, getConversion func(stmt *sql.Stmt) ([]any, error)) ([]any, error) {
+	query, err := db.prepare(request)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	defer query.Close()
 
-	return stmt
+	return getConversion(query)
 }
 
 // MorRequest is responsible for making a request to the Mor database via SSH tunnel.
 func MorRequest(request string, getConversion func(stmt *sql.Stmt) ([]any, error)) (res []any, err error) {
+	err = MorSession(func(db *Db) error {
+		res, err = db.request(request, getConversion)
+		return err
+	})
+	return res, err
+}
+
+// MorSession opens a connection to the Mor database via SSH tunnel and runs several requests on it with the provided function.
+func MorSession(run func(db *Db) error) error {
 	// Retrieve database connection details from configuration.
 	DbIpMor := viper.GetString("DB_IP_MOR")
 	DbPortMor := viper.GetString("DB_PORT_MOR")
@@ -68,11 +82,11 @@ func MorRequest(request string, getConversion func(stmt *sql.Stmt) ([]any, error
 	// Read the SSH private key file and create an SSH signer.
 	key, err := os.ReadFile(dbSshKeyMor)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	signer, err := ssh.ParsePrivateKeyWithPassphrase(key, []byte(dbSshKeyPassMor))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Configure the SSH client.
@@ -87,7 +101,7 @@ func MorRequest(request string, getConversion func(stmt *sql.Stmt) ([]any, error
 	// Establish an SSH connection.
 	sshcon, errSSH := ssh.Dial("tcp", fmt.Sprintf("%s:%d", dbSshIpMor, dbSshPortMorInt), sshConfig)
 	if errSSH != nil {
-		return nil, err
+		return errSSH
 	}
 	defer sshcon.Close()
 
@@ -101,13 +115,31 @@ func MorRequest(request string, getConversion func(stmt *sql.Stmt) ([]any, error
 	dsn := fmt.Sprintf("%s:%s@mysql+tcp(%s)/%s", dbUserMor, dbPassMor, DbIpMor+":"+DbPortMor, dbNameMor)
 
 	// Create a new database connection.
-	db := newDb(dsn)
+	db, err := newDb(dsn)
+	if err != nil {
+		return err
+	}
 	defer db.db.Close()
 
-	// Prepare the SQL query and execute it.
-	query := db.prepare(request)
-	defer query.Close()
+	// Run the requests on the connection.
+	return run(db)
+}
 
-	// Retrieve and return the results using the provided function.
-	return getConversion(query)
+// SQL expression of the direction of the calls, with the predicates of getDirectionFilter.
+const directionExpression = "CASE WHEN c.did_id > 0 THEN 'incoming' WHEN c.provider_id > 0 THEN 'outgoing' ELSE 'local' END"
+
+// Build the SQL filter of the calls of a direction: incoming (received on a DID, even when forwarded to a provider), outgoing (sent
+// to a provider), local or all.
+func getDirectionFilter(direction string) (string, error) {
+	switch direction {
+	case "outgoing":
+		return "c.provider_id > 0 AND c.did_id = 0", nil
+	case "incoming":
+		return "c.did_id > 0", nil
+	case "local":
+		return "c.provider_id = 0 AND c.did_id = 0", nil
+	case "all", "":
+		return "1 = 1", nil
+	}
+	return "", fmt.Errorf("invalid direction %s", direction)
 }
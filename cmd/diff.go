@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringP("report", "r", "", "The report of the export files (default: found from the header)")
+	diffCmd.Flags().StringP("keys", "k", "", "The comma separated key columns aligning the rows (default: the key columns of the report)")
+	diffCmd.Flags().BoolP("all", "a", false, "Also write the unchanged rows")
+}
+
+// Read the header row of an export file.
+func readExportHeader(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.Read()
+}
+
+// Define the main Cobra command for comparing two export files.
+var diffCmd = &cobra.Command{
+	Use:   "diff [old_file] [new_file]",
+	Short: "Compare two export files of the same report and export the changed, added and removed rows.",
+	Long: `Compare two export files produced by the same report, aligned on the key columns of the report (e.g., Device group, Country, Destination and Prefix for morCallsPricesByDestinationsByDeviceGroupsByProviders, Did for morIncomingCallsDuration), and export the changed, added (NEW) and removed (REMOVED) rows with the values of both files side by side and the absolute and percentage deltas of the numeric columns.
+
+Usage:
+  diff [old_file] [new_file] [-r [report]] [-k [keys]] [-a]
+
+Flags:
+  -r, --report string   The report of the export files (default: found from the header)
+  -k, --keys string     The comma separated key columns aligning the rows (default: the key columns of the report)
+  -a, --all             Also write the unchanged rows
+
+Example:
+  diff 2023_02_01_08_00_00_export.csv 2023_03_01_08_00_00_export.csv
+  diff old.csv new.csv -k "Did"
+
+The values of the old file are in the "(previous)" columns and the deltas are the new values minus the old values. The generated CSV file is named with a timestamp and saved in the current working directory.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the report, the key columns and the option from the command-line flags.
+		report, _ := cmd.Flags().GetString("report")
+		keysStr, _ := cmd.Flags().GetString("keys")
+		all, _ := cmd.Flags().GetBool("all")
+
+		// Read the header of the new file.
+		header, err := readExportHeader(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Find the key columns: given, of the given report or of the report found from the header.
+		var keyColumns []string
+		if keysStr != "" {
+			for _, column := range strings.Split(keysStr, ",") {
+				keyColumns = append(keyColumns, strings.TrimSpace(column))
+			}
+		} else {
+			if report == "" {
+				found := false
+				report, found = findReportOfHeader(header)
+				if !found {
+					fmt.Println("Unknown report. Please use the report flag or the keys flag")
+					return
+				}
+			}
+			definition, found := reports[report]
+			if !found {
+				fmt.Println("Invalid report " + report + ". Please use a report accepting the compare option")
+				return
+			}
+			keyColumns = definition.keyColumns
+		}
+
+		// Display the files and the key columns for the user's reference.
+		fmt.Println("diff called with old file: " + args[0] + ", new file: " + args[1] + " and keys: " + strings.Join(keyColumns, ", "))
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Compare the files on their actual days.
+		statuses, err := compareExportFiles(args[1], args[0], filename, keyColumns, time.Time{}, time.Time{}, !all)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Display the number of rows by status.
+		fmt.Printf("%d changed, %d added, %d removed, %d unchanged rows\n", statuses["CHANGED"], statuses["NEW"], statuses["REMOVED"], statuses[""])
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// incrementalState represents the watermark of an incremental export: the last exported call ID and call date.
+type incrementalState struct {
+	LastID       int64  `json:"lastId"`
+	LastCallDate string `json:"lastCallDate"`
+}
+
+// Watermark of the running incremental export, nil when the export is not incremental.
+// The calls exports move it to their last exported call.
+var incrementalWatermark *incrementalState
+
+// Flags which do not change the watermark of an incremental export.
+var incrementalIgnoredFlags = []string{"dateStart", "dateEnd", "incremental", "stateFile", "lookback"}
+
+// Read the watermarks of the state file by export, empty when it does not exist yet.
+func readIncrementalStates(filename string) (map[string]incrementalState, error) {
+	states := make(map[string]incrementalState)
+	content, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return states, json.Unmarshal(content, &states)
+}
+
+// Write the watermarks of the state file, through a temporary file so that it is never partially written.
+func writeIncrementalStates(filename string, states map[string]incrementalState) error {
+	content, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename+".tmp", content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// Resolve the state file relative to the directory of the configuration file, so that the watermarks are kept whatever the
+// working directory of the export (e.g., the temporary directories of the daemon jobs).
+func getIncrementalStateFile(stateFile string) string {
+	if filepath.IsAbs(stateFile) || viper.ConfigFileUsed() == "" {
+		return stateFile
+	}
+	configFile, err := filepath.Abs(viper.ConfigFileUsed())
+	if err != nil {
+		return stateFile
+	}
+	return filepath.Join(filepath.Dir(configFile), stateFile)
+}
+
+// Build the key of an export in the state file: the command name and its other flags, each set of filters having its own watermark.
+func getIncrementalKey(cmd *cobra.Command) string {
+	var flags []string
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		for _, ignoredFlag := range incrementalIgnoredFlags {
+			if flag.Name == ignoredFlag {
+				return
+			}
+		}
+		flags = append(flags, flag.Name+"="+flag.Value.String())
+	})
+	sort.Strings(flags)
+
+	return strings.TrimSpace(cmd.Name() + " " + strings.Join(flags, " "))
+}
+
+// Add the incremental option to an export command. The calls exports (daily false) only export the calls with an ID over the watermark
+// and a call date over the last call date minus the lookback, the daily exports (daily true) export again the days from the last end
+// date minus the lookback. The start date is only used by the first export and the end date is now when it is not given.
+func registerIncremental(cmd *cobra.Command, daily bool) {
+	cmd.Flags().Bool("incremental", false, "Only export the calls since the last incremental export")
+	cmd.Flags().String("stateFile", "incremental_state.json", "The file of the watermarks of the incremental exports, relative to the directory of the configuration file")
+	cmd.Flags().Int("lookback", 60, "The number of minutes before the watermark checked for the calls inserted late")
+
+	// Run the export from the watermark when the option is given.
+	run := cmd.Run
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		incremental, _ := cmd.Flags().GetBool("incremental")
+		if !incremental {
+			run(cmd, args)
+			return
+		}
+		if compare := cmd.Flags().Lookup("compare"); compare != nil && compare.Value.String() != "" {
+			fmt.Println("The incremental export cannot be compared with another period")
+			return
+		}
+
+		// Read the watermark of the export.
+		stateFile, _ := cmd.Flags().GetString("stateFile")
+		stateFile = getIncrementalStateFile(stateFile)
+		lookback, _ := cmd.Flags().GetInt("lookback")
+		if lookback < 0 {
+			fmt.Println("Invalid lookback. Please use a number of minutes greater than or equal to 0")
+			return
+		}
+		states, err := readIncrementalStates(stateFile)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		key := getIncrementalKey(cmd)
+		state, found := states[key]
+
+		// Compute the period of the export from the watermark, up to now when no end date is given.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+		if dateEndStr == "" {
+			dateEndStr = time.Now().Format("2006-01-02 15:04:05")
+		}
+		if found {
+			lastCallDate, err := time.Parse("2006-01-02 15:04:05", state.LastCallDate)
+			if err != nil {
+				fmt.Printf("Invalid watermark %s of %s in %s\n", state.LastCallDate, key, stateFile)
+				return
+			}
+			dateStart := lastCallDate.Add(-time.Duration(lookback) * time.Minute)
+			if daily {
+				dateStart = time.Date(dateStart.Year(), dateStart.Month(), dateStart.Day(), 0, 0, 0, 0, time.UTC)
+			}
+			dateStartStr = dateStart.Format("2006-01-02 15:04:05")
+		}
+
+		// Name the export file to check that the export succeeded.
+		filename := getExportFilename(cmd)
+
+		// Run the export with the watermark.
+		incrementalWatermark = &incrementalState{LastID: state.LastID, LastCallDate: state.LastCallDate}
+		defer func() { incrementalWatermark = nil }()
+		runReport(run, cmd, args, reportRun{dateStart: dateStartStr, dateEnd: dateEndStr, filename: filename})
+		if _, err := os.Stat(filename); err != nil {
+			return
+		}
+
+		// Save the watermark: the last exported call, the end date for the daily exports.
+		if daily {
+			incrementalWatermark.LastCallDate = dateEndStr
+		}
+		if incrementalWatermark.LastCallDate == "" {
+			return
+		}
+		states[key] = *incrementalWatermark
+		if err := writeIncrementalStates(stateFile, states); err != nil {
+			log.Fatal(err)
+		}
+
+		// Log a message indicating the saved watermark.
+		log.Printf("%s watermark saved in %s: call ID %d, call date %s", key, stateFile, incrementalWatermark.LastID, incrementalWatermark.LastCallDate)
+	}
+}
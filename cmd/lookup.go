@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nyaruka/phonenumbers"
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(lookupCmd)
+	lookupCmd.Flags().StringP("dateStart", "s", "", "The start date of the lookup (default: 30 days before now)")
+	lookupCmd.Flags().StringP("dateEnd", "e", "", "The end date of the lookup (default: now)")
+	lookupCmd.Flags().IntP("limit", "l", 100, "The maximum number of displayed calls, the most recent ones")
+}
+
+// ModelLookupCall represents a call where the looked up number is the source or the destination.
+type ModelLookupCall struct {
+	ID          string
+	CallDate    string
+	Src         string
+	Dst         string
+	Disposition string
+	Billsec     int
+	Price       float64
+	Provider    string
+	SrcDevice   string
+	DstDevice   string
+}
+
+// ModelLookupDid represents the DID record of the looked up number.
+type ModelLookupDid struct {
+	Did         string
+	Status      string
+	Provider    string
+	Username    string
+	Extension   *string
+	Description *string
+	ClosedTill  *string
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelLookupCall(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelLookupCall
+
+		if err := rows.Scan(
+			&msg.ID,
+			&msg.CallDate,
+			&msg.Src,
+			&msg.Dst,
+			&msg.Disposition,
+			&msg.Billsec,
+			&msg.Price,
+			&msg.Provider,
+			&msg.SrcDevice,
+			&msg.DstDevice,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Retrieve DID data from the database and return it as a slice of models.
+func getModelLookupDid(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelLookupDid
+
+		if err := rows.Scan(
+			&msg.Did,
+			&msg.Status,
+			&msg.Provider,
+			&msg.Username,
+			&msg.Extension,
+			&msg.Description,
+			&msg.ClosedTill,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Build the formats a number can be stored with in the MOR database (international with and without prefix, national).
+func getNumberVariants(number string) ([]string, error) {
+	// Keep only the digits of the number.
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+	if digits == "" {
+		return nil, fmt.Errorf("invalid number %s", number)
+	}
+
+	variants := []string{digits}
+	e164, _, _, err := getNumberInformation(number)
+	if err == nil {
+		phoneNumber, _ := phonenumbers.Parse(e164, "")
+		international := strings.TrimPrefix(e164, "+")
+		variants = append(variants, international, "+"+international, "00"+international, "0"+phonenumbers.GetNationalSignificantNumber(phoneNumber))
+	}
+
+	// Remove the duplicated variants.
+	var uniqueVariants []string
+	found := make(map[string]bool)
+	for _, variant := range variants {
+		if !found[variant] {
+			found[variant] = true
+			uniqueVariants = append(uniqueVariants, variant)
+		}
+	}
+
+	return uniqueVariants, nil
+}
+
+// Convert a nullable string to a string.
+func getNullableString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// Define the main Cobra command for looking up a number.
+var lookupCmd = &cobra.Command{
+	Use:   "lookup [number]",
+	Short: "Display the calls history, the DID record and the statistics of one phone number or DID.",
+	Long: `Display the calls of the MOR database where a phone number or a DID is the source or the destination for a specified date range, its DID record and summary statistics. The number can be given in any format, it is normalized and searched in its international and national formats.
+
+Usage:
+  lookup [number] [-s [start_date] -e [end_date]] [-l [limit]]
+
+Flags:
+  -s, --dateStart string   The start date of the lookup (e.g., 'YYYY-MM-DD HH:mm:SS', default: 30 days before now)
+  -e, --dateEnd string     The end date of the lookup (e.g., 'YYYY-MM-DD HH:mm:SS', default: now)
+  -l, --limit int          The maximum number of displayed calls, the most recent ones (default 100)
+
+Example:
+  lookup "+33 6 12 34 56 78"
+  lookup 0612345678 -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59"
+
+This command display the result in the terminal, the statistics include all the calls of the period even when the displayed calls are limited.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings and the limit from the command-line flags.
+		dateStartStr, _ := cmd.Flags().GetString("dateStart")
+		dateEndStr, _ := cmd.Flags().GetString("dateEnd")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		// Look up the last 30 days when no dates are given.
+		now := time.Now()
+		if dateStartStr == "" {
+			dateStartStr = now.AddDate(0, 0, -30).Format("2006-01-02 15:04:05")
+		}
+		if dateEndStr == "" {
+			dateEndStr = now.Format("2006-01-02 15:04:05")
+		}
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Build the formats of the number.
+		variants, err := getNumberVariants(args[0])
+		if err != nil {
+			fmt.Println("Invalid number. Please use a phone number or a DID (e.g., '+33612345678')")
+			return
+		}
+		variantsList := "'" + strings.Join(variants, "','") + "'"
+
+		// Display the number and the date information for the user's reference.
+		fmt.Println("lookup called with number: " + args[0] + " (" + strings.Join(variants, ", ") + ") and dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Construct the SQL query of the DID record.
+		requestDid := fmt.Sprintf(`SELECT
+			d.did AS Did,
+			d.status AS Status,
+			IFNULL(p.name, '') AS Provider,
+			IFNULL(u.username, '') AS Username,
+			dv.extension AS Extension,
+			dv.description AS Description,
+			d.closed_till AS ClosedTill
+		FROM mor.dids d
+		LEFT JOIN mor.providers p ON d.provider_id = p.id
+		LEFT JOIN mor.users u ON d.user_id = u.id
+		LEFT JOIN mor.devices dv ON d.device_id = dv.id
+		WHERE d.did IN (%s);`, variantsList)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(requestDid)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		resultsDid, err := MorRequest(requestDid, getModelLookupDid)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Construct the SQL query of the calls.
+		request := fmt.Sprintf(`SELECT
+			c.id AS ID,
+			c.calldate AS CallDate,
+			c.src AS Src,
+			c.dst AS Dst,
+			c.disposition AS Disposition,
+			c.billsec AS Billsec,
+			IFNULL(c.provider_price, 0) AS Price,
+			IFNULL(p.name, '') AS Provider,
+			IFNULL(sdv.extension, '') AS SrcDevice,
+			IFNULL(ddv.extension, '') AS DstDevice
+		FROM mor.calls c
+		LEFT JOIN mor.providers p ON c.provider_id = p.id
+		LEFT JOIN mor.devices sdv ON c.src_device_id = sdv.id
+		LEFT JOIN mor.devices ddv ON c.dst_device_id = ddv.id
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			(c.src IN (%s) OR c.dst IN (%s))
+		ORDER BY c.calldate DESC;`, dateStartStr, dateEndStr, variantsList, variantsList)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelLookupCall)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Create a list to hold the results casted to the desired data model.
+		var resultsCasted []ModelLookupCall
+		for _, result := range results {
+			resultsCasted = append(resultsCasted, result.(ModelLookupCall))
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		// Display the DID records.
+		fmt.Fprintln(writer, "\nDID record")
+		if len(resultsDid) == 0 {
+			fmt.Fprintln(writer, "Not a DID of the MOR database")
+		} else {
+			fmt.Fprintln(writer, "Did\tStatus\tProvider\tUsername\tExtension\tDescription\tUpdateDate")
+			for _, result := range resultsDid {
+				did := result.(ModelLookupDid)
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", did.Did, did.Status, did.Provider, did.Username, getNullableString(did.Extension), getNullableString(did.Description), getNullableString(did.ClosedTill))
+			}
+		}
+
+		// Compute the summary statistics of the calls.
+		incomingCalls := 0
+		outgoingCalls := 0
+		answeredCalls := 0
+		billsec := 0
+		price := float64(0)
+		counterparts := make(map[string]bool)
+		isVariant := make(map[string]bool)
+		for _, variant := range variants {
+			isVariant[variant] = true
+		}
+		for _, call := range resultsCasted {
+			if isVariant[call.Dst] {
+				incomingCalls++
+				counterparts[call.Src] = true
+			} else {
+				outgoingCalls++
+				counterparts[call.Dst] = true
+			}
+			if call.Disposition == "ANSWERED" {
+				answeredCalls++
+				billsec += call.Billsec
+			}
+			price += call.Price
+		}
+
+		// Display the summary statistics.
+		fmt.Fprintln(writer, "\nSummary")
+		fmt.Fprintf(writer, "Calls\t%d\n", len(resultsCasted))
+		fmt.Fprintf(writer, "Calls to the number\t%d\n", incomingCalls)
+		fmt.Fprintf(writer, "Calls from the number\t%d\n", outgoingCalls)
+		fmt.Fprintf(writer, "Answered calls\t%d (%s%%)\n", answeredCalls, formatPercent(answeredCalls, len(resultsCasted)))
+		fmt.Fprintf(writer, "Billed seconds\t%d\n", billsec)
+		fmt.Fprintf(writer, "Provider price\t%s\n", formatPrice(price))
+		fmt.Fprintf(writer, "Different numbers\t%d\n", len(counterparts))
+		if len(resultsCasted) > 0 {
+			fmt.Fprintf(writer, "First call\t%s\n", resultsCasted[len(resultsCasted)-1].CallDate)
+			fmt.Fprintf(writer, "Last call\t%s\n", resultsCasted[0].CallDate)
+		}
+
+		// Display the most recent calls.
+		fmt.Fprintf(writer, "\nCalls (%d most recent)\n", limit)
+		fmt.Fprintln(writer, "ID\tCall date\tSrc\tDst\tDisposition\tBillsec\tPrice\tProvider\tSrc device\tDst device")
+		for i, call := range resultsCasted {
+			if i >= limit {
+				break
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", call.ID, call.CallDate, call.Src, call.Dst, call.Disposition, call.Billsec, formatPrice(call.Price), call.Provider, call.SrcDevice, call.DstDevice)
+		}
+
+		writer.Flush()
+	},
+}
@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morBillingSummaryByUsersByResellers)
+	morBillingSummaryByUsersByResellers.Flags().StringP("dateStart", "s", "", "The start date of the export")
+	morBillingSummaryByUsersByResellers.Flags().StringP("dateEnd", "e", "", "The end date of the export")
+	morBillingSummaryByUsersByResellers.Flags().StringP("user", "u", "", "The username of the only customer to export")
+}
+
+// ModelMorBillingSummaryCalls represents the billed calls of a user to a destination type.
+type ModelMorBillingSummaryCalls struct {
+	Reseller        string
+	User            string
+	DestinationType string
+	Calls           int
+	UserBillsec     int
+	UserPrice       float64
+	ResellerBillsec int
+	ResellerPrice   float64
+}
+
+// ModelMorBillingSummaryDids represents the number of active DIDs of a user.
+type ModelMorBillingSummaryDids struct {
+	Reseller string
+	User     string
+	Dids     int
+}
+
+// ModelMorBillingSummaryCustomer represents the billing summary of one customer.
+type ModelMorBillingSummaryCustomer struct {
+	Reseller string
+	User     string
+	Calls    []ModelMorBillingSummaryCalls
+	Dids     int
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelMorBillingSummaryCalls(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorBillingSummaryCalls
+
+		if err := rows.Scan(
+			&msg.Reseller,
+			&msg.User,
+			&msg.DestinationType,
+			&msg.Calls,
+			&msg.UserBillsec,
+			&msg.UserPrice,
+			&msg.ResellerBillsec,
+			&msg.ResellerPrice,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Retrieve DID data from the database and return it as a slice of models.
+func getModelMorBillingSummaryDids(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorBillingSummaryDids
+
+		if err := rows.Scan(
+			&msg.Reseller,
+			&msg.User,
+			&msg.Dids,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Write the billing summary of a customer in its own file.
+func writeBillingSummaryFile(filename string, dateStartStr string, dateEndStr string, customer *ModelMorBillingSummaryCustomer, resellerInvoice bool) {
+	// Create and open the output file for writing.
+	outputFile, err := os.Create(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer outputFile.Close()
+
+	// Write the customer and the period.
+	fmt.Fprintf(outputFile, "Customer;%s\n", customer.User)
+	if customer.Reseller != "" && !resellerInvoice {
+		fmt.Fprintf(outputFile, "Reseller;%s\n", customer.Reseller)
+	}
+	fmt.Fprintf(outputFile, "Period;%s;%s\n\n", dateStartStr, dateEndStr)
+
+	// Write the header row to the output file.
+	fmt.Fprintln(outputFile, "User;Destination type;Calls;Billed minutes;Price")
+
+	// Process and write each destination type to the output file, priced with the reseller price for the reseller invoices.
+	totalCalls := 0
+	totalBillsec := 0
+	totalPrice := float64(0)
+	for _, calls := range customer.Calls {
+		billsec := calls.UserBillsec
+		price := calls.UserPrice
+		if resellerInvoice {
+			billsec = calls.ResellerBillsec
+			price = calls.ResellerPrice
+		}
+
+		fmt.Fprintf(outputFile, "%s;%s;%d;%s;%s\n", calls.User, calls.DestinationType, calls.Calls, strconv.FormatFloat(float64(billsec)/60, 'f', 2, 64), formatPrice(price))
+
+		totalCalls += calls.Calls
+		totalBillsec += billsec
+		totalPrice += price
+	}
+
+	// Write the totals and the active DIDs.
+	fmt.Fprintf(outputFile, "Total;;%d;%s;%s\n\n", totalCalls, strconv.FormatFloat(float64(totalBillsec)/60, 'f', 2, 64), formatPrice(totalPrice))
+	fmt.Fprintf(outputFile, "Active DIDs;%d\n", customer.Dids)
+
+	// Log a message indicating the filename of the exported data.
+	log.Printf("%s exported", filename)
+}
+
+// Define the main Cobra command for exporting the billing summaries.
+var morBillingSummaryByUsersByResellers = &cobra.Command{
+	Use:   "morBillingSummaryByUsersByResellers",
+	Short: "Export one billing summary file per user and per reseller, ready to attach to an invoice.",
+	Long: `Export the billing summary of each user and each reseller from the MOR database, in one CSV file per customer. Each file includes the period, the calls, billed minutes and price by destination type with their total, and the number of active DIDs.
+
+Usage:
+  morBillingSummaryByUsersByResellers -s [start_date] -e [end_date] [-u [user]]
+
+Flags:
+  -s, --dateStart string   The start date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string     The end date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -u, --user string        The username of the only customer to export (e.g., 'accounting')
+
+Example:
+  morBillingSummaryByUsersByResellers -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59"
+
+This command export the billing summary of the answered outgoing calls. The destination type is the subcode of the MOR destination (e.g., FIX, MOB). The user files are priced with the user price. The reseller files include the calls and the DIDs of all the users of the reseller, priced with the reseller price. The calls and the DIDs are attributed to the reseller of the calls (like the margin report), the DIDs of a user without calls during the period to the owner of the user. The generated CSV files are named with a timestamp and the customer username and saved in the current working directory. This export does not accept the --compare option: it writes one file by customer instead of a single export.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings from the command-line flags.
+		dateStartStr, _ := cmd.Flags().GetString("dateStart")
+		dateEndStr, _ := cmd.Flags().GetString("dateEnd")
+		user, _ := cmd.Flags().GetString("user")
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morBillingSummaryByUsersByResellers called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			IFNULL(r.username, '') AS Reseller,
+			u.username AS User,
+			IFNULL(NULLIF(d.subcode, ''), 'OTHER') AS DestinationType,
+			count(*) AS Calls,
+			IFNULL(SUM(c.user_billsec), 0) AS UserBillsec,
+			IFNULL(SUM(c.user_price), 0) AS UserPrice,
+			IFNULL(SUM(c.reseller_billsec), 0) AS ResellerBillsec,
+			IFNULL(SUM(c.reseller_price), 0) AS ResellerPrice
+		FROM mor.calls c
+		INNER JOIN mor.users u ON c.user_id = u.id
+		LEFT JOIN mor.users r ON c.reseller_id = r.id
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.provider_id > 0 AND
+			c.disposition = 'ANSWERED'
+		GROUP BY Reseller, User, DestinationType
+		ORDER BY Reseller, User, DestinationType;`, dateStartStr, dateEndStr)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorBillingSummaryCalls)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Construct the SQL query of the active DIDs, attributed to the reseller of the calls of their user like the calls,
+		// to the owner of their user when the user has no calls during the period.
+		requestDids := fmt.Sprintf(`SELECT
+			IFNULL(r.username, '') AS Reseller,
+			u.username AS User,
+			count(*) AS Dids
+		FROM mor.dids d
+		INNER JOIN mor.users u ON d.user_id = u.id
+		LEFT JOIN (
+			SELECT c.user_id, MAX(c.reseller_id) AS reseller_id
+			FROM mor.calls c
+			WHERE
+				c.calldate > '%s' AND
+				c.calldate < '%s' AND
+				c.provider_id > 0 AND
+				c.disposition = 'ANSWERED'
+			GROUP BY c.user_id
+		) cr ON cr.user_id = u.id
+		LEFT JOIN mor.users r ON r.id = IFNULL(cr.reseller_id, u.owner_id)
+		WHERE d.status = 'active'
+		GROUP BY Reseller, User;`, dateStartStr, dateEndStr)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(requestDids)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		resultsDids, err := MorRequest(requestDids, getModelMorBillingSummaryDids)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Group the calls and the DIDs by user and by reseller.
+		var usernames []string
+		var resellerUsernames []string
+		customers := make(map[string]*ModelMorBillingSummaryCustomer)
+		resellers := make(map[string]*ModelMorBillingSummaryCustomer)
+		getCustomers := func(reseller string, username string) (*ModelMorBillingSummaryCustomer, *ModelMorBillingSummaryCustomer) {
+			customer, found := customers[username]
+			if !found {
+				customer = &ModelMorBillingSummaryCustomer{Reseller: reseller, User: username}
+				customers[username] = customer
+				usernames = append(usernames, username)
+			}
+			if reseller == "" {
+				return customer, nil
+			}
+			resellerCustomer, found := resellers[reseller]
+			if !found {
+				resellerCustomer = &ModelMorBillingSummaryCustomer{User: reseller}
+				resellers[reseller] = resellerCustomer
+				resellerUsernames = append(resellerUsernames, reseller)
+			}
+			return customer, resellerCustomer
+		}
+
+		for _, result := range results {
+			calls := result.(ModelMorBillingSummaryCalls)
+			customer, resellerCustomer := getCustomers(calls.Reseller, calls.User)
+			customer.Calls = append(customer.Calls, calls)
+			if resellerCustomer != nil {
+				resellerCustomer.Calls = append(resellerCustomer.Calls, calls)
+			}
+		}
+
+		for _, result := range resultsDids {
+			dids := result.(ModelMorBillingSummaryDids)
+			customer, resellerCustomer := getCustomers(dids.Reseller, dids.User)
+			customer.Dids += dids.Dids
+			if resellerCustomer != nil {
+				resellerCustomer.Dids += dids.Dids
+			}
+		}
+
+		// Generate a filename prefix for the output files.
+		now := time.Now()
+		filenamePrefix := fmt.Sprintf("%d_%02d_%02d_%02d_%02d_%02d", now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second())
+
+		// Write one file per user.
+		for _, username := range usernames {
+			if user != "" && username != user {
+				continue
+			}
+			filename := fmt.Sprintf("%s_%s_billing.csv", filenamePrefix, sanitizeFilename(username))
+			writeBillingSummaryFile(filename, dateStartStr, dateEndStr, customers[username], false)
+		}
+
+		// Write one file per reseller with the calls of its users.
+		for _, resellerUsername := range resellerUsernames {
+			if user != "" && resellerUsername != user {
+				continue
+			}
+			filename := fmt.Sprintf("%s_%s_reseller_billing.csv", filenamePrefix, sanitizeFilename(resellerUsername))
+			writeBillingSummaryFile(filename, dateStartStr, dateEndStr, resellers[resellerUsername], true)
+		}
+	},
+}
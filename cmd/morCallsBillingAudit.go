@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsBillingAudit)
+	morCallsBillingAudit.Flags().StringP("dateStart", "s", "", "The start date of the audit")
+	morCallsBillingAudit.Flags().StringP("dateEnd", "e", "", "The end date of the audit")
+	morCallsBillingAudit.Flags().Float64P("tolerance", "t", 0.01, "The maximum difference between the MOR price and the recomputed price")
+}
+
+// ModelMorCallsBillingAudit represents an answered outgoing call with its prices and the tariffs of its provider and user.
+type ModelMorCallsBillingAudit struct {
+	ID               string
+	CallDate         string
+	Dst              string
+	Prefix           string
+	Billsec          int
+	ProviderPrice    float64
+	UserPrice        float64
+	ProviderTariffID string
+	UserTariffID     string
+	Provider         string
+	User             string
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelMorCallsBillingAudit(rows *sql.Rows) (ModelMorCallsBillingAudit, error) {
+	var msg ModelMorCallsBillingAudit
+
+	err := rows.Scan(
+		&msg.ID,
+		&msg.CallDate,
+		&msg.Dst,
+		&msg.Prefix,
+		&msg.Billsec,
+		&msg.ProviderPrice,
+		&msg.UserPrice,
+		&msg.ProviderTariffID,
+		&msg.UserTariffID,
+		&msg.Provider,
+		&msg.User,
+	)
+
+	return msg, err
+}
+
+// Define the main Cobra command for auditing the calls prices.
+var morCallsBillingAudit = &cobra.Command{
+	Use:   "morCallsBillingAudit",
+	Short: "Re-rate the answered outgoing calls with the MOR tariffs and export the calls billed with a different price.",
+	Long: `Load the provider, user wholesale and user retail tariffs from the MOR database (rates, increments, minimum times, connection fees), re-rate each answered outgoing call from its prefix and billed seconds, and export the calls where the recomputed price differs from the provider price or the user price. The CSV will include the following columns: Call ID, Call date, Dst, Prefix, Billsec, Side, Tariff, Name, Rate, Increment, Min time, Connection fee, MOR price, Recomputed price, Difference, Status.
+
+Usage:
+  morCallsBillingAudit -s [start_date] -e [end_date] [-t [tolerance]]
+
+Flags:
+  -s, --dateStart string    The start date of the audit (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string      The end date of the audit (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -t, --tolerance float     The maximum difference between the MOR price and the recomputed price (default 0.01)
+
+Example:
+  morCallsBillingAudit -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59" -t 0.001
+
+This command recompute the price of each call as the rate per minute of the billed seconds, at least the minimum time and rounded up to the increment, plus the connection fee, with the rate detail matching the time and the day type (WD or FD) of the call. The retail tariffs are re-rated with the price of their first minute range, rounded up to their minutes, plus their event prices. The Side column is Provider or User and the Status column is MISMATCH, NO RATE when the prefix is not in the tariff, NO RATE FOR TIME when no rate detail of the prefix matches the time of the call, or NOT RATED when the tariff of the provider or the user is unknown. The tariffs are expected in the same currency as the calls prices. The generated CSV file is named with a timestamp and saved in the current working directory. This audit does not accept the --compare option: its rows are single calls, which have no key to align between two periods.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings and the tolerance from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+		tolerance, _ := cmd.Flags().GetFloat64("tolerance")
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morCallsBillingAudit called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Load the rate decks of the tariffs.
+		rateDecks, err := getMorRateDecks()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			c.id AS ID,
+			c.calldate AS CallDate,
+			c.dst AS Dst,
+			c.prefix AS Prefix,
+			c.billsec AS Billsec,
+			IFNULL(c.provider_price, 0) AS ProviderPrice,
+			IFNULL(c.user_price, 0) AS UserPrice,
+			IFNULL(p.tariff_id, 0) AS ProviderTariffID,
+			IFNULL(u.tariff_id, 0) AS UserTariffID,
+			IFNULL(p.name, '') AS Provider,
+			IFNULL(u.username, '') AS User
+		FROM mor.calls c
+		LEFT JOIN mor.providers p ON c.provider_id = p.id
+		LEFT JOIN mor.users u ON c.user_id = u.id
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.provider_id > 0 AND
+			c.disposition = 'ANSWERED'
+		ORDER BY c.calldate, c.id;`, dateStartStr, dateEndStr)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Call ID;Call date;Dst;Prefix;Billsec;Side;Tariff;Name;Rate;Increment;Min time;Connection fee;MOR price;Recomputed price;Difference;Status")
+
+		// Send the SQL request to the MorRequest function and re-rate each call while it is read.
+		checkedCalls := 0
+		notRatedCalls := 0
+		discrepancies := 0
+		totalDifference := float64(0)
+		_, err = MorRequest(request, func(stmt *sql.Stmt) ([]any, error) {
+			rows, err := stmt.Query()
+			if err != nil {
+				return nil, err
+			}
+
+			defer rows.Close()
+
+			for rows.Next() {
+				call, err := getModelMorCallsBillingAudit(rows)
+				if err != nil {
+					return nil, err
+				}
+				callDate, err := time.Parse("2006-01-02 15:04:05", call.CallDate)
+				if err != nil {
+					return nil, err
+				}
+				checkedCalls++
+
+				// Re-rate the provider side and the user side of the call.
+				sides := []struct {
+					name     string
+					tariffID string
+					owner    string
+					price    float64
+				}{
+					{"Provider", call.ProviderTariffID, call.Provider, call.ProviderPrice},
+					{"User", call.UserTariffID, call.User, call.UserPrice},
+				}
+				for _, side := range sides {
+					deck, found := rateDecks[side.tariffID]
+					if !found {
+						notRatedCalls++
+						fmt.Fprintf(outputFile, "%s;%s;%s;%s;%d;%s;%s;%s;;;;;%s;;;NOT RATED\n", call.ID, call.CallDate, call.Dst, call.Prefix, call.Billsec, side.name, side.tariffID, side.owner, formatPrice(side.price))
+						continue
+					}
+
+					// Find the rate from the prefix of the call, from its destination otherwise.
+					rate, found := deck.findRate(call.Prefix, callDate)
+					if !found {
+						rate, found = deck.findRate(removeZero(call.Dst), callDate)
+					}
+					if !found {
+						// Tell a prefix missing from the tariff from a prefix without rate detail at the time of the call.
+						status := "NO RATE"
+						if deck.hasPrefix(call.Prefix) || deck.hasPrefix(removeZero(call.Dst)) {
+							status = "NO RATE FOR TIME"
+						}
+						discrepancies++
+						fmt.Fprintf(outputFile, "%s;%s;%s;%s;%d;%s;%s;%s;;;;;%s;;;%s\n", call.ID, call.CallDate, call.Dst, call.Prefix, call.Billsec, side.name, side.tariffID, side.owner, formatPrice(side.price), status)
+						continue
+					}
+
+					// Export the calls where the difference is over the tolerance.
+					recomputedPrice := computeCallPrice(rate, call.Billsec)
+					difference := side.price - recomputedPrice
+					if math.Abs(difference) > tolerance {
+						discrepancies++
+						totalDifference += difference
+						fmt.Fprintf(outputFile, "%s;%s;%s;%s;%d;%s;%s;%s;%s;%d;%d;%s;%s;%s;%s;MISMATCH\n", call.ID, call.CallDate, call.Dst, call.Prefix, call.Billsec, side.name, rate.Tariff, side.owner, strconv.FormatFloat(rate.Rate, 'f', -1, 64), rate.Increment, rate.MinTime, strconv.FormatFloat(rate.ConnectionFee, 'f', -1, 64), strconv.FormatFloat(side.price, 'f', 4, 64), strconv.FormatFloat(recomputedPrice, 'f', 4, 64), strconv.FormatFloat(difference, 'f', 4, 64))
+					}
+				}
+			}
+
+			return nil, rows.Err()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Display the summary of the audit.
+		fmt.Printf("%d calls checked, %d prices not re-rated (unknown tariff), %d discrepancies, total difference %s\n", checkedCalls, notRatedCalls, discrepancies, strconv.FormatFloat(totalDifference, 'f', 4, 64))
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
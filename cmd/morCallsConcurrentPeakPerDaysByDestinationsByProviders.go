@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsConcurrentPeakPerDaysByDestinationsByProviders)
+	registerReport(morCallsConcurrentPeakPerDaysByDestinationsByProviders, "Day;Dimension;Value;Peak calls", "Day", "Dimension", "Value")
+	registerIncremental(morCallsConcurrentPeakPerDaysByDestinationsByProviders, true)
+	morCallsConcurrentPeakPerDaysByDestinationsByProviders.Flags().StringP("dateStart", "s", "", "The start date of the export")
+	morCallsConcurrentPeakPerDaysByDestinationsByProviders.Flags().StringP("dateEnd", "e", "", "The end date of the export")
+	morCallsConcurrentPeakPerDaysByDestinationsByProviders.Flags().IntP("maxDuration", "m", 240, "The maximum duration in minutes of the calls started before the start date")
+}
+
+// ModelMorCallsConcurrentPeakCall represents one outgoing call with its start date and its duration.
+type ModelMorCallsConcurrentPeakCall struct {
+	CallDate    string
+	Duration    int
+	Provider    string
+	DeviceGroup string
+	Destination string
+	Prefix      string
+}
+
+// ModelMorCallsConcurrentPeak represents the peak of simultaneous calls of a day.
+type ModelMorCallsConcurrentPeak struct {
+	Day      string
+	Calls    int
+	PeakTime time.Time
+}
+
+// concurrentCallsEvent represents the start (+1) or the end (-1) of a call.
+type concurrentCallsEvent struct {
+	time  time.Time
+	delta int
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelMorCallsConcurrentPeakCall(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCallsConcurrentPeakCall
+
+		if err := rows.Scan(
+			&msg.CallDate,
+			&msg.Duration,
+			&msg.Provider,
+			&msg.DeviceGroup,
+			&msg.Destination,
+			&msg.Prefix,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Compute the peak of simultaneous calls of each day with a sweep-line over the start and end events of the calls.
+// The events before the period start are moved to the period start, so that the calls started before it count from it.
+func getConcurrentCallsPeaks(events []concurrentCallsEvent, periodStart time.Time) []ModelMorCallsConcurrentPeak {
+	for i := range events {
+		if events[i].time.Before(periodStart) {
+			events[i].time = periodStart
+		}
+	}
+
+	// Sort the events by time, the ends before the starts so that consecutive calls are not counted as simultaneous.
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].time.Equal(events[j].time) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].time.Before(events[j].time)
+	})
+
+	var peaks []ModelMorCallsConcurrentPeak
+	currentCalls := 0
+	for _, event := range events {
+		day := event.time.Format("2006-01-02")
+
+		// Start a new day with the calls still running at midnight.
+		if len(peaks) == 0 || peaks[len(peaks)-1].Day != day {
+			if len(peaks) > 0 {
+				// Add the days crossed by running calls without any event.
+				lastDay, _ := time.Parse("2006-01-02", peaks[len(peaks)-1].Day)
+				for nextDay := lastDay.AddDate(0, 0, 1); nextDay.Format("2006-01-02") < day && currentCalls > 0; nextDay = nextDay.AddDate(0, 0, 1) {
+					peaks = append(peaks, ModelMorCallsConcurrentPeak{Day: nextDay.Format("2006-01-02"), Calls: currentCalls, PeakTime: nextDay})
+				}
+			}
+			midnight, _ := time.Parse("2006-01-02", day)
+			peaks = append(peaks, ModelMorCallsConcurrentPeak{Day: day, Calls: currentCalls, PeakTime: midnight})
+		}
+
+		// Apply the event and keep the first time the peak has been reached.
+		currentCalls += event.delta
+		if currentCalls > peaks[len(peaks)-1].Calls {
+			peaks[len(peaks)-1].Calls = currentCalls
+			peaks[len(peaks)-1].PeakTime = event.time
+		}
+	}
+
+	return peaks
+}
+
+// Define the main Cobra command for exporting the peaks of simultaneous calls.
+var morCallsConcurrentPeakPerDaysByDestinationsByProviders = &cobra.Command{
+	Use:   "morCallsConcurrentPeakPerDaysByDestinationsByProviders",
+	Short: "Export the peak of simultaneous outgoing calls per days by destinations, providers and device groups.",
+	Long: `Export the real peak of simultaneous outgoing calls from the MOR database per days, computed from the calls start date and duration, in total and by destination countries, providers and device groups. The CSV will include the following columns: Day, Dimension, Value, Peak calls, Peak time.
+
+Usage:
+  morCallsConcurrentPeakPerDaysByDestinationsByProviders -s [start_date] -e [end_date] [-m [max_duration]]
+
+Flags:
+  -s, --dateStart string   The start date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string     The end date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -m, --maxDuration int    The maximum duration in minutes of the calls started before the start date (default 240)
+
+Example:
+  morCallsConcurrentPeakPerDaysByDestinationsByProviders -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59"
+
+This command export the peak of simultaneous calls sent to the providers of the providersID list, to size the SIP trunks. Each call occupies a channel from its start date during its whole duration (ringing included), the calls without duration are ignored. The calls started up to maxDuration minutes before the start date and still running at the start date are counted from the start date. The Dimension column is Total, Country, Provider or Device group and the Value column is the country, provider or device group name. The generated CSV file is named with a timestamp and saved in the current working directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+		maxDuration, _ := cmd.Flags().GetInt("maxDuration")
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		if maxDuration < 0 {
+			fmt.Println("Invalid maxDuration. Please use a number of minutes greater than or equal to 0")
+			return
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morCallsConcurrentPeakPerDaysByDestinationsByProviders called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Build the device group SQL filter.
+		srcDevicesIDFilter, _ := getDeviceGroupFilter("c.src_device_id")
+
+		// Construct the SQL query with placeholders, with the calls started before the start date and still running at the start date.
+		request := fmt.Sprintf(`SELECT
+			c.calldate AS CallDate,
+			c.duration AS Duration,
+			p.name AS Provider,
+			%s AS DeviceGroup,
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix
+		FROM mor.calls c
+		INNER JOIN mor.providers p ON c.provider_id = p.id
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > DATE_SUB('%s', INTERVAL %d MINUTE) AND
+			c.calldate < '%s' AND
+			DATE_ADD(c.calldate, INTERVAL c.duration SECOND) > '%s' AND
+			c.provider_id IN (%s) AND
+			c.duration > 0;`, srcDevicesIDFilter, dateStartStr, maxDuration, dateEndStr, dateStartStr, strings.Join(providersID, ","))
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCallsConcurrentPeakCall)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Create a list to hold the results casted to the desired data model.
+		var resultsCasted []ModelMorCallsConcurrentPeakCall
+		for _, result := range results {
+			resultsCasted = append(resultsCasted, result.(ModelMorCallsConcurrentPeakCall))
+		}
+
+		// Build the start and end events of the calls for each dimension.
+		var dimensions []string
+		dimensionsEvents := make(map[string][]concurrentCallsEvent)
+		countriesRegion := make(map[string]string)
+		for _, oneResult := range resultsCasted {
+			callStart, err := time.Parse("2006-01-02 15:04:05", oneResult.CallDate)
+			if err != nil {
+				log.Fatal(err)
+			}
+			callEnd := callStart.Add(time.Duration(oneResult.Duration) * time.Second)
+
+			// Retrieve the country of the destination once per prefix.
+			displayRegion, found := countriesRegion[oneResult.Prefix]
+			if !found {
+				_, displayRegion = getCountryFromPrefix(oneResult.Prefix, oneResult.Destination)
+				countriesRegion[oneResult.Prefix] = displayRegion
+			}
+
+			keys := []string{"Total;", "Country;" + displayRegion, "Provider;" + oneResult.Provider}
+			if oneResult.DeviceGroup != "" {
+				keys = append(keys, "Device group;"+oneResult.DeviceGroup)
+			}
+			for _, key := range keys {
+				if _, found := dimensionsEvents[key]; !found {
+					dimensions = append(dimensions, key)
+				}
+				dimensionsEvents[key] = append(dimensionsEvents[key], concurrentCallsEvent{time: callStart, delta: 1}, concurrentCallsEvent{time: callEnd, delta: -1})
+			}
+		}
+		sort.Strings(dimensions)
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Day;Dimension;Value;Peak calls;Peak time")
+
+		// Process and write the peaks of each dimension to the output file.
+		for _, dimension := range dimensions {
+			for _, peak := range getConcurrentCallsPeaks(dimensionsEvents[dimension], dateStart) {
+				// Ignore the days after the export reached by the calls still running at the end date.
+				if peak.Day > dateEnd.Format("2006-01-02") {
+					continue
+				}
+				fmt.Fprintf(outputFile, "%s;%s;%d;%s\n", peak.Day, dimension, peak.Calls, peak.PeakTime.Format("2006-01-02 15:04:05"))
+			}
+		}
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetConcurrentCallsPeaks(t *testing.T) {
+	type call struct {
+		start    string
+		duration time.Duration
+	}
+	type peak struct {
+		day      string
+		calls    int
+		peakTime string
+	}
+
+	tests := []struct {
+		name        string
+		periodStart string
+		calls       []call
+		want        []peak
+	}{
+		{
+			name:        "overlapping calls",
+			periodStart: "2023-01-02 00:00:00",
+			calls:       []call{{"2023-01-02 10:00:00", 10 * time.Minute}, {"2023-01-02 10:05:00", 10 * time.Minute}, {"2023-01-02 10:08:00", time.Minute}},
+			want:        []peak{{"2023-01-02", 3, "2023-01-02 10:08:00"}},
+		},
+		{
+			name:        "consecutive calls are not simultaneous",
+			periodStart: "2023-01-02 00:00:00",
+			calls:       []call{{"2023-01-02 10:00:00", 5 * time.Minute}, {"2023-01-02 10:05:00", 5 * time.Minute}},
+			want:        []peak{{"2023-01-02", 1, "2023-01-02 10:00:00"}},
+		},
+		{
+			name:        "call running at midnight",
+			periodStart: "2023-01-02 00:00:00",
+			calls:       []call{{"2023-01-02 23:50:00", 20 * time.Minute}, {"2023-01-03 08:00:00", time.Minute}},
+			want:        []peak{{"2023-01-02", 1, "2023-01-02 23:50:00"}, {"2023-01-03", 1, "2023-01-03 00:00:00"}},
+		},
+		{
+			name:        "call running on days without events",
+			periodStart: "2023-01-02 00:00:00",
+			calls:       []call{{"2023-01-02 23:00:00", 50 * time.Hour}},
+			want:        []peak{{"2023-01-02", 1, "2023-01-02 23:00:00"}, {"2023-01-03", 1, "2023-01-03 00:00:00"}, {"2023-01-04", 1, "2023-01-04 00:00:00"}, {"2023-01-05", 1, "2023-01-05 00:00:00"}},
+		},
+		{
+			name:        "call started before the period start",
+			periodStart: "2023-01-02 00:00:00",
+			calls:       []call{{"2023-01-01 23:30:00", time.Hour}, {"2023-01-02 00:10:00", 5 * time.Minute}},
+			want:        []peak{{"2023-01-02", 2, "2023-01-02 00:10:00"}},
+		},
+		{
+			name:        "call started before the period start, peak at the period start",
+			periodStart: "2023-01-02 00:00:00",
+			calls:       []call{{"2023-01-01 23:30:00", time.Hour}, {"2023-01-01 23:40:00", time.Hour}},
+			want:        []peak{{"2023-01-02", 2, "2023-01-02 00:00:00"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var events []concurrentCallsEvent
+			for _, call := range test.calls {
+				start, _ := time.Parse("2006-01-02 15:04:05", call.start)
+				events = append(events, concurrentCallsEvent{time: start, delta: 1}, concurrentCallsEvent{time: start.Add(call.duration), delta: -1})
+			}
+			periodStart, _ := time.Parse("2006-01-02 15:04:05", test.periodStart)
+
+			got := getConcurrentCallsPeaks(events, periodStart)
+			if len(got) != len(test.want) {
+				t.Fatalf("getConcurrentCallsPeaks returned %d days, want %d: %v", len(got), len(test.want), got)
+			}
+			for i, want := range test.want {
+				if got[i].Day != want.day || got[i].Calls != want.calls || got[i].PeakTime.Format("2006-01-02 15:04:05") != want.peakTime {
+					t.Errorf("day %d = %s, %d, %s, want %s, %d, %s", i, got[i].Day, got[i].Calls, got[i].PeakTime.Format("2006-01-02 15:04:05"), want.day, want.calls, want.peakTime)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsDetailRecords)
+	registerIncremental(morCallsDetailRecords, false)
+	morCallsDetailRecords.Flags().StringP("dateStart", "s", "", "The start date of the export")
+	morCallsDetailRecords.Flags().StringP("dateEnd", "e", "", "The end date of the export")
+	morCallsDetailRecords.Flags().StringP("columns", "c", strings.Join(cdrDefaultColumns, ","), "The comma separated columns of the export")
+	morCallsDetailRecords.Flags().StringP("direction", "d", "all", "The direction of the calls (incoming, outgoing, local or all)")
+	morCallsDetailRecords.Flags().StringP("disposition", "x", "", "The disposition of the calls (ANSWERED, NO ANSWER, BUSY or FAILED)")
+	morCallsDetailRecords.Flags().StringP("provider", "p", "", "A part of the provider name of the calls")
+	morCallsDetailRecords.Flags().StringP("device", "v", "", "The device ID of the calls (source or destination)")
+	morCallsDetailRecords.Flags().StringP("number", "n", "", "The pattern of the source or destination number, * matches any digits")
+}
+
+// cdrColumn represents a column of the CDR export with its header and its SQL expression, empty for the enriched columns.
+type cdrColumn struct {
+	header string
+	sql    string
+}
+
+// Columns of the CDR export.
+var cdrColumns = map[string]cdrColumn{
+	"id":             {"ID", "c.id"},
+	"calldate":       {"Call date", "c.calldate"},
+	"src":            {"Src", "c.src"},
+	"dst":            {"Dst", "c.dst"},
+	"disposition":    {"Disposition", "c.disposition"},
+	"billsec":        {"Billsec", "c.billsec"},
+	"duration":       {"Duration", "c.duration"},
+	"hangupcause":    {"Hangup cause", "c.hangupcause"},
+	"prefix":         {"Prefix", "c.prefix"},
+	"destination":    {"Destination", "d.name"},
+	"provider_price": {"Provider price", "c.provider_price"},
+	"user_price":     {"User price", "c.user_price"},
+	"reseller_price": {"Reseller price", "c.reseller_price"},
+	"provider":       {"Provider", "p.name"},
+	"device":         {"Device", "CONCAT(sdv.extension, ' ', IFNULL(sdv.description, ''))"},
+	"dst_device":     {"Dst device", "CONCAT(ddv.extension, ' ', IFNULL(ddv.description, ''))"},
+	"user":           {"User", "u.username"},
+	"src_e164":       {"Src E.164", ""},
+	"src_country":    {"Src country", ""},
+	"src_type":       {"Src type", ""},
+	"dst_e164":       {"Dst E.164", ""},
+	"dst_country":    {"Dst country", ""},
+	"dst_type":       {"Dst type", ""},
+}
+
+// Default columns of the CDR export.
+var cdrDefaultColumns = []string{"calldate", "src", "dst", "disposition", "billsec", "duration", "provider_price", "user_price", "provider", "device", "user", "dst_e164", "dst_country", "dst_type"}
+
+// Dispositions of the MOR calls.
+var callDispositions = []string{"ANSWERED", "NO ANSWER", "BUSY", "FAILED"}
+
+// Retrieve the E.164 format, the country and the type of a number, empty if it cannot be parsed.
+func getNumberEnrichment(number string) (string, string, string) {
+	e164, regionCode, numberType, err := getNumberInformation(number)
+	if err != nil {
+		return "", "", ""
+	}
+	return e164, getCountryName(regionCode), getNumberTypeName(numberType)
+}
+
+// Build the SQL filters of the CDR export from the flags, the values are checked as they are inserted in the query.
+func getCdrFilters(cmd *cobra.Command) ([]string, error) {
+	direction, _ := cmd.Flags().GetString("direction")
+	disposition, _ := cmd.Flags().GetString("disposition")
+	provider, _ := cmd.Flags().GetString("provider")
+	device, _ := cmd.Flags().GetString("device")
+	number, _ := cmd.Flags().GetString("number")
+
+	var filters []string
+
+	// Filter the direction.
+	directionFilter, err := getDirectionFilter(direction)
+	if err != nil {
+		return nil, err
+	}
+	filters = append(filters, directionFilter)
+
+	// Filter the disposition.
+	if disposition != "" {
+		found := false
+		for _, oneDisposition := range callDispositions {
+			if strings.EqualFold(disposition, oneDisposition) {
+				filters = append(filters, fmt.Sprintf("c.disposition = '%s'", oneDisposition))
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid disposition %s", disposition)
+		}
+	}
+
+	// Filter a part of the provider name.
+	if provider != "" {
+		if strings.ContainsAny(provider, `'"\%_`) {
+			return nil, fmt.Errorf("invalid provider %s", provider)
+		}
+		filters = append(filters, fmt.Sprintf("p.name LIKE '%%%s%%'", provider))
+	}
+
+	// Filter the source or destination device.
+	if device != "" {
+		if strings.Trim(device, "0123456789") != "" {
+			return nil, fmt.Errorf("invalid device %s", device)
+		}
+		filters = append(filters, fmt.Sprintf("(c.src_device_id = %s OR c.dst_device_id = %s)", device, device))
+	}
+
+	// Filter the source or destination number pattern.
+	if number != "" {
+		if strings.Trim(number, "0123456789+*") != "" {
+			return nil, fmt.Errorf("invalid number %s", number)
+		}
+		pattern := strings.ReplaceAll(strings.TrimPrefix(number, "+"), "*", "%")
+		filters = append(filters, fmt.Sprintf("(c.src LIKE '%s' OR c.dst LIKE '%s')", pattern, pattern))
+	}
+
+	return filters, nil
+}
+
+// Define the main Cobra command for exporting the calls detail records.
+var morCallsDetailRecords = &cobra.Command{
+	Use:   "morCallsDetailRecords",
+	Short: "Export the calls detail records with the chosen columns, enriched with the normalized numbers.",
+	Long: `Export the calls detail records of the MOR database for a specified date range, with the chosen columns, joined with the provider, device and user names and enriched with the E.164 format, the country and the type of the numbers. The rows are written while they are read from the database.
+
+Usage:
+  morCallsDetailRecords -s [start_date] -e [end_date] [-c [columns]] [-d [direction]] [-x [disposition]] [-p [provider]] [-v [device]] [-n [number]]
+
+Flags:
+  -s, --dateStart string     The start date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string       The end date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -c, --columns string       The comma separated columns of the export (default: calldate,src,dst,disposition,billsec,duration,provider_price,user_price,provider,device,user,dst_e164,dst_country,dst_type)
+  -d, --direction string     The direction of the calls: incoming, outgoing, local or all (default "all")
+  -x, --disposition string   The disposition of the calls: ANSWERED, NO ANSWER, BUSY or FAILED
+  -p, --provider string      A part of the provider name of the calls (e.g., 'sfr')
+  -v, --device string        The device ID of the calls, source or destination (e.g., '181')
+  -n, --number string        The pattern of the source or destination number, * matches any digits (e.g., '3361*')
+
+Available columns:
+  id, calldate, src, dst, disposition, billsec, duration, hangupcause, prefix, destination, provider_price, user_price, reseller_price, provider, device, dst_device, user, src_e164, src_country, src_type, dst_e164, dst_country, dst_type
+
+Example:
+  morCallsDetailRecords -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59" -d outgoing -x ANSWERED -c "calldate,dst,billsec,provider_price,dst_country"
+
+The incoming calls are the calls received on a DID, even when they are forwarded to a provider, the outgoing calls are the other calls sent to a provider. The generated CSV file is named with a timestamp and saved in the current working directory. This export does not accept the --compare option: its rows are single calls, which have no key to align between two periods.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings and the columns from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+		columnsStr, _ := cmd.Flags().GetString("columns")
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Check the requested columns and build their headers.
+		var columns []string
+		var headers []string
+		for _, column := range strings.Split(columnsStr, ",") {
+			column = strings.ToLower(strings.TrimSpace(column))
+			oneColumn, found := cdrColumns[column]
+			if !found {
+				fmt.Println("Invalid column " + column + ". Please use the columns listed in the help")
+				return
+			}
+			columns = append(columns, column)
+			headers = append(headers, oneColumn.header)
+		}
+
+		// Build the filters of the calls.
+		filters, err := getCdrFilters(cmd)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		// Only export the calls inserted after the watermark in the incremental mode.
+		if incrementalWatermark != nil {
+			filters = append(filters, fmt.Sprintf("c.id > %d", incrementalWatermark.LastID))
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morCallsDetailRecords called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Construct the SQL query with placeholders, the ID and the call date are always selected for the watermark and the numbers for the enriched columns.
+		var selectedColumns []string
+		srcEnriched := false
+		dstEnriched := false
+		for _, column := range columns {
+			if cdrColumns[column].sql != "" {
+				selectedColumns = append(selectedColumns, fmt.Sprintf("IFNULL(%s, '')", cdrColumns[column].sql))
+			} else if strings.HasPrefix(column, "src_") {
+				srcEnriched = true
+			} else {
+				dstEnriched = true
+			}
+		}
+		selectedColumns = append(selectedColumns, "c.id", "c.calldate", "IFNULL(c.src, '')", "IFNULL(c.dst, '')")
+
+		request := fmt.Sprintf(`SELECT
+			%s
+		FROM mor.calls c
+		LEFT JOIN mor.providers p ON c.provider_id = p.id
+		LEFT JOIN mor.devices sdv ON c.src_device_id = sdv.id
+		LEFT JOIN mor.devices ddv ON c.dst_device_id = ddv.id
+		LEFT JOIN mor.users u ON c.user_id = u.id
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			%s
+		ORDER BY c.calldate, c.id;`, strings.Join(selectedColumns, ",\n			"), dateStartStr, dateEndStr, strings.Join(filters, " AND\n			"))
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, strings.Join(headers, ";"))
+
+		// Send the SQL request to the MorRequest function and write each row while it is read.
+		exportedCalls := 0
+		_, err = MorRequest(request, func(stmt *sql.Stmt) ([]any, error) {
+			rows, err := stmt.Query()
+			if err != nil {
+				return nil, err
+			}
+
+			defer rows.Close()
+
+			values := make([]string, len(selectedColumns))
+			valuesPointers := make([]any, len(selectedColumns))
+			for i := range values {
+				valuesPointers[i] = &values[i]
+			}
+
+			for rows.Next() {
+				if err := rows.Scan(valuesPointers...); err != nil {
+					return nil, err
+				}
+
+				// Enrich the source and destination numbers when requested.
+				enrichedValues := make(map[string]string)
+				if srcEnriched {
+					enrichedValues["src_e164"], enrichedValues["src_country"], enrichedValues["src_type"] = getNumberEnrichment(values[len(values)-2])
+				}
+				if dstEnriched {
+					enrichedValues["dst_e164"], enrichedValues["dst_country"], enrichedValues["dst_type"] = getNumberEnrichment(values[len(values)-1])
+				}
+
+				// Write the requested columns to the output file.
+				var line []string
+				value := 0
+				for _, column := range columns {
+					if cdrColumns[column].sql == "" {
+						line = append(line, enrichedValues[column])
+					} else {
+						line = append(line, values[value])
+						value++
+					}
+				}
+				fmt.Fprintln(outputFile, strings.Join(line, ";"))
+				exportedCalls++
+
+				// Move the watermark to the last exported call.
+				if incrementalWatermark != nil {
+					id, _ := strconv.ParseInt(values[len(values)-4], 10, 64)
+					if id > incrementalWatermark.LastID {
+						incrementalWatermark.LastID = id
+					}
+					if values[len(values)-3] > incrementalWatermark.LastCallDate {
+						incrementalWatermark.LastCallDate = values[len(values)-3]
+					}
+				}
+			}
+
+			return nil, rows.Err()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported (%d calls)", filename, exportedCalls)
+	},
+}
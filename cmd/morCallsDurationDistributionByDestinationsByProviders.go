@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsDurationDistributionByDestinationsByProviders)
+	registerReport(morCallsDurationDistributionByDestinationsByProviders, "Dimension;Value;Calls;Average", "Dimension", "Value")
+	morCallsDurationDistributionByDestinationsByProviders.Flags().StringP("dateStart", "s", "", "The start date of the export")
+	morCallsDurationDistributionByDestinationsByProviders.Flags().StringP("dateEnd", "e", "", "The end date of the export")
+	morCallsDurationDistributionByDestinationsByProviders.Flags().StringP("buckets", "b", "0,10,30,60,120,300,600", "The comma separated lower bounds in seconds of the histogram buckets")
+	morCallsDurationDistributionByDestinationsByProviders.Flags().IntP("shortCall", "c", 10, "The billed seconds under which a call is short")
+}
+
+// ModelMorCallsDurationDistribution represents the number of answered calls with the same billed seconds.
+type ModelMorCallsDurationDistribution struct {
+	Provider    string
+	DeviceGroup string
+	Destination string
+	Prefix      string
+	Billsec     int
+	Calls       int
+}
+
+// durationDistribution represents the number of calls of each billed seconds of a dimension.
+type durationDistribution struct {
+	calls        int
+	billsec      int
+	billsecCalls map[int]int
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelMorCallsDurationDistribution(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCallsDurationDistribution
+
+		if err := rows.Scan(
+			&msg.Provider,
+			&msg.DeviceGroup,
+			&msg.Destination,
+			&msg.Prefix,
+			&msg.Billsec,
+			&msg.Calls,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Compute the percentiles of the billed seconds from the number of calls of each billed seconds.
+func getDurationPercentiles(distribution *durationDistribution, percentiles []float64) []int {
+	// Sort the billed seconds.
+	var billsecs []int
+	for billsec := range distribution.billsecCalls {
+		billsecs = append(billsecs, billsec)
+	}
+	sort.Ints(billsecs)
+
+	// Walk the cumulative number of calls up to the rank of each percentile (nearest-rank method).
+	values := make([]int, len(percentiles))
+	for i, percentile := range percentiles {
+		rank := int(math.Ceil(percentile * float64(distribution.calls) / 100))
+		if rank < 1 {
+			rank = 1
+		}
+		cumulatedCalls := 0
+		for _, billsec := range billsecs {
+			cumulatedCalls += distribution.billsecCalls[billsec]
+			if cumulatedCalls >= rank {
+				values[i] = billsec
+				break
+			}
+		}
+	}
+
+	return values
+}
+
+// Define the main Cobra command for exporting the calls duration distribution.
+var morCallsDurationDistributionByDestinationsByProviders = &cobra.Command{
+	Use:   "morCallsDurationDistributionByDestinationsByProviders",
+	Short: "Export the distribution and the percentiles of the answered outgoing calls duration by destinations, providers and device groups.",
+	Long: `Export the distribution of the billed seconds of the answered outgoing calls from the MOR database by destination countries, providers and device groups: histogram, average, median, 90th, 95th and 99th percentiles and short calls ratio. The CSV will include the following columns: Dimension, Value, Calls, Average, Median, P90, P95, P99, Short calls (%), and one column per histogram bucket.
+
+Usage:
+  morCallsDurationDistributionByDestinationsByProviders -s [start_date] -e [end_date] [-b [buckets]] [-c [short_call]]
+
+Flags:
+  -s, --dateStart string   The start date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string     The end date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -b, --buckets string     The comma separated lower bounds in seconds of the histogram buckets (default "0,10,30,60,120,300,600")
+  -c, --shortCall int      The billed seconds under which a call is short (default 10)
+
+Example:
+  morCallsDurationDistributionByDestinationsByProviders -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59" -b "0,5,30,60,180" -c 5
+
+This command export the distribution of the calls sent to the providers of the providersID list. The database returns the number of calls of each billed seconds, so the percentiles are exact without loading each call. The Dimension column is Total, Country, Provider or Device group, the durations are in seconds. The generated CSV file is named with a timestamp and saved in the current working directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings and the options from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+		bucketsStr, _ := cmd.Flags().GetString("buckets")
+		shortCall, _ := cmd.Flags().GetInt("shortCall")
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Parse the histogram buckets, they must be increasing and start from 0.
+		var buckets []int
+		for _, bucketStr := range strings.Split(bucketsStr, ",") {
+			bucket, err := strconv.Atoi(strings.TrimSpace(bucketStr))
+			if err != nil || (len(buckets) == 0 && bucket != 0) || (len(buckets) > 0 && bucket <= buckets[len(buckets)-1]) {
+				fmt.Println("Invalid buckets format. Please use increasing comma separated seconds starting from 0 (e.g., '0,10,30,60')")
+				return
+			}
+			buckets = append(buckets, bucket)
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morCallsDurationDistributionByDestinationsByProviders called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Build the device group SQL filter.
+		srcDevicesIDFilter, _ := getDeviceGroupFilter("c.src_device_id")
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			p.name AS Provider,
+			%s AS DeviceGroup,
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix,
+			c.billsec AS Billsec,
+			count(*) AS Calls
+		FROM mor.calls c
+		INNER JOIN mor.providers p ON c.provider_id = p.id
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.provider_id IN (%s) AND
+			c.disposition = 'ANSWERED'
+		GROUP BY Provider, DeviceGroup, Destination, Prefix, Billsec;`, srcDevicesIDFilter, dateStartStr, dateEndStr, strings.Join(providersID, ","))
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCallsDurationDistribution)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Add the number of calls of each billed seconds to the distribution of each dimension.
+		var dimensions []string
+		distributions := make(map[string]*durationDistribution)
+		countriesRegion := make(map[string]string)
+		for _, result := range results {
+			oneResult := result.(ModelMorCallsDurationDistribution)
+
+			// Retrieve the country of the destination once per prefix.
+			displayRegion, found := countriesRegion[oneResult.Prefix]
+			if !found {
+				_, displayRegion = getCountryFromPrefix(oneResult.Prefix, oneResult.Destination)
+				countriesRegion[oneResult.Prefix] = displayRegion
+			}
+
+			keys := []string{"Total;", "Country;" + displayRegion, "Provider;" + oneResult.Provider}
+			if oneResult.DeviceGroup != "" {
+				keys = append(keys, "Device group;"+oneResult.DeviceGroup)
+			}
+			for _, key := range keys {
+				distribution, found := distributions[key]
+				if !found {
+					distribution = &durationDistribution{billsecCalls: make(map[int]int)}
+					distributions[key] = distribution
+					dimensions = append(dimensions, key)
+				}
+				distribution.calls += oneResult.Calls
+				distribution.billsec += oneResult.Billsec * oneResult.Calls
+				distribution.billsecCalls[oneResult.Billsec] += oneResult.Calls
+			}
+		}
+		sort.Strings(dimensions)
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file, with the bounds of each bucket.
+		var bucketsHeader []string
+		for i, bucket := range buckets {
+			if i+1 < len(buckets) {
+				bucketsHeader = append(bucketsHeader, fmt.Sprintf("%d-%d", bucket, buckets[i+1]))
+			} else {
+				bucketsHeader = append(bucketsHeader, fmt.Sprintf("%d+", bucket))
+			}
+		}
+		fmt.Fprintln(outputFile, "Dimension;Value;Calls;Average;Median;P90;P95;P99;Short calls (%);"+strings.Join(bucketsHeader, ";"))
+
+		// Process and write the distribution of each dimension to the output file.
+		for _, dimension := range dimensions {
+			distribution := distributions[dimension]
+
+			// Fill the histogram and count the short calls.
+			histogram := make([]int, len(buckets))
+			shortCalls := 0
+			for billsec, calls := range distribution.billsecCalls {
+				bucket := sort.Search(len(buckets), func(i int) bool { return buckets[i] > billsec }) - 1
+				histogram[bucket] += calls
+				if billsec < shortCall {
+					shortCalls += calls
+				}
+			}
+			var histogramValues []string
+			for _, calls := range histogram {
+				histogramValues = append(histogramValues, strconv.Itoa(calls))
+			}
+
+			// Compute the average and the percentiles.
+			average := float64(distribution.billsec) / float64(distribution.calls)
+			percentiles := getDurationPercentiles(distribution, []float64{50, 90, 95, 99})
+
+			// Write the formatted result to the output file.
+			fmt.Fprintf(outputFile, "%s;%d;%s;%d;%d;%d;%d;%s;%s\n", dimension, distribution.calls, strconv.FormatFloat(average, 'f', 2, 64), percentiles[0], percentiles[1], percentiles[2], percentiles[3], formatPercent(shortCalls, distribution.calls), strings.Join(histogramValues, ";"))
+		}
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
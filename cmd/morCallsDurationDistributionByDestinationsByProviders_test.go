@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDurationPercentiles(t *testing.T) {
+	percentiles := []float64{50, 90, 95, 99}
+
+	tests := []struct {
+		name         string
+		billsecCalls map[int]int
+		want         []int
+	}{
+		{"single call", map[int]int{42: 1}, []int{42, 42, 42, 42}},
+		{"one call per duration", map[int]int{1: 1, 2: 1, 3: 1, 4: 1, 5: 1, 6: 1, 7: 1, 8: 1, 9: 1, 10: 1}, []int{5, 9, 10, 10}},
+		{"repeated durations", map[int]int{10: 50, 60: 40, 300: 9, 3600: 1}, []int{10, 60, 300, 300}},
+		{"rank on a bucket boundary", map[int]int{30: 90, 120: 10}, []int{30, 30, 120, 120}},
+		{"no calls", map[int]int{}, []int{0, 0, 0, 0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			distribution := &durationDistribution{billsecCalls: test.billsecCalls}
+			for billsec, calls := range test.billsecCalls {
+				distribution.calls += calls
+				distribution.billsec += billsec * calls
+			}
+			if got := getDurationPercentiles(distribution, percentiles); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("getDurationPercentiles = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsErlangBChannelsByProvidersByDeviceGroups)
+	registerReport(morCallsErlangBChannelsByProvidersByDeviceGroups, "Dimension;Value;Busy hour", "Dimension", "Value", "Growth (%)")
+	morCallsErlangBChannelsByProvidersByDeviceGroups.Flags().StringP("dateStart", "s", "", "The start date of the export")
+	morCallsErlangBChannelsByProvidersByDeviceGroups.Flags().StringP("dateEnd", "e", "", "The end date of the export")
+	morCallsErlangBChannelsByProvidersByDeviceGroups.Flags().Float64P("blocking", "b", 0.01, "The target blocking probability of the recommended channels")
+	morCallsErlangBChannelsByProvidersByDeviceGroups.Flags().StringP("growth", "g", "0,10,25,50", "The comma separated traffic growth scenarios in percent")
+}
+
+// Find the minimum number of channels which carry the traffic with a blocking probability lower or equal to the target.
+func erlangBChannels(erlangs float64, targetBlocking float64) (int, float64) {
+	if erlangs <= 0 {
+		return 0, 0
+	}
+
+	// Compute the Erlang B blocking probability iteratively until it reaches the target.
+	channels := 0
+	blocking := 1.0
+	for blocking > targetBlocking {
+		channels++
+		blocking = erlangs * blocking / (float64(channels) + erlangs*blocking)
+	}
+	return channels, blocking
+}
+
+// Add the seconds of the call to the traffic of each hour it crosses.
+func addCallHoursTraffic(hoursTraffic map[time.Time]float64, callStart time.Time, callEnd time.Time) {
+	for hour := callStart.Truncate(time.Hour); hour.Before(callEnd); hour = hour.Add(time.Hour) {
+		start := callStart
+		if hour.After(start) {
+			start = hour
+		}
+		end := callEnd
+		if hour.Add(time.Hour).Before(end) {
+			end = hour.Add(time.Hour)
+		}
+		hoursTraffic[hour] += end.Sub(start).Seconds()
+	}
+}
+
+// Define the main Cobra command for exporting the Erlang B channels recommendations.
+var morCallsErlangBChannelsByProvidersByDeviceGroups = &cobra.Command{
+	Use:   "morCallsErlangBChannelsByProvidersByDeviceGroups",
+	Short: "Export the busy hour traffic (Erlangs) and the recommended channels (Erlang B) by providers and device groups.",
+	Long: `Export the busy hour traffic of the outgoing calls from the MOR database in Erlangs, in total and by providers and device groups, and the number of channels recommended by Erlang B for a target blocking probability and several traffic growth scenarios. The CSV will include the following columns: Dimension, Value, Busy hour, Busy hour traffic (Erlangs), Growth (%), Projected traffic (Erlangs), Channels, Blocking (%).
+
+Usage:
+  morCallsErlangBChannelsByProvidersByDeviceGroups -s [start_date] -e [end_date] -b [blocking] -g [growth]
+
+Flags:
+  -s, --dateStart string   The start date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string     The end date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -b, --blocking float     The target blocking probability of the recommended channels (default 0.01)
+  -g, --growth string      The comma separated traffic growth scenarios in percent (default "0,10,25,50")
+
+Example:
+  morCallsErlangBChannelsByProvidersByDeviceGroups -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59" -b 0.01 -g "0,10,25,50"
+
+This command export the busy hour traffic of the calls sent to the providers of the providersID list. The traffic of each hour is the sum of the seconds of the calls during this hour (ringing included) divided by 3600, the busy hour is the hour with the most traffic. For each growth scenario, the busy hour traffic is increased by the growth percent and the channels are the minimum number of channels with an Erlang B blocking probability lower or equal to the target. The generated CSV file is named with a timestamp and saved in the current working directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+		targetBlocking, _ := cmd.Flags().GetFloat64("blocking")
+		growthStr, _ := cmd.Flags().GetString("growth")
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Check the target blocking probability.
+		if targetBlocking <= 0 || targetBlocking >= 1 {
+			fmt.Println("Invalid blocking. Please use a probability between 0 and 1 (e.g., '0.01')")
+			return
+		}
+
+		// Parse the growth scenarios.
+		var growths []float64
+		for _, oneGrowthStr := range strings.Split(growthStr, ",") {
+			growth, err := strconv.ParseFloat(strings.TrimSpace(oneGrowthStr), 64)
+			if err != nil || growth <= -100 {
+				fmt.Println("Invalid growth format. Please use comma separated percents (e.g., '0,10,25,50')")
+				return
+			}
+			growths = append(growths, growth)
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morCallsErlangBChannelsByProvidersByDeviceGroups called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Build the device group SQL filter.
+		srcDevicesIDFilter, _ := getDeviceGroupFilter("c.src_device_id")
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			c.calldate AS CallDate,
+			c.duration AS Duration,
+			p.name AS Provider,
+			%s AS DeviceGroup,
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix
+		FROM mor.calls c
+		INNER JOIN mor.providers p ON c.provider_id = p.id
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.provider_id IN (%s) AND
+			c.duration > 0;`, srcDevicesIDFilter, dateStartStr, dateEndStr, strings.Join(providersID, ","))
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCallsConcurrentPeakCall)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Create a list to hold the results casted to the desired data model.
+		var resultsCasted []ModelMorCallsConcurrentPeakCall
+		for _, result := range results {
+			resultsCasted = append(resultsCasted, result.(ModelMorCallsConcurrentPeakCall))
+		}
+
+		// Sum the traffic of each hour for each dimension.
+		var dimensions []string
+		dimensionsHoursTraffic := make(map[string]map[time.Time]float64)
+		for _, oneResult := range resultsCasted {
+			callStart, err := time.Parse("2006-01-02 15:04:05", oneResult.CallDate)
+			if err != nil {
+				log.Fatal(err)
+			}
+			callEnd := callStart.Add(time.Duration(oneResult.Duration) * time.Second)
+
+			keys := []string{"Total;", "Provider;" + oneResult.Provider}
+			if oneResult.DeviceGroup != "" {
+				keys = append(keys, "Device group;"+oneResult.DeviceGroup)
+			}
+			for _, key := range keys {
+				if _, found := dimensionsHoursTraffic[key]; !found {
+					dimensions = append(dimensions, key)
+					dimensionsHoursTraffic[key] = make(map[time.Time]float64)
+				}
+				addCallHoursTraffic(dimensionsHoursTraffic[key], callStart, callEnd)
+			}
+		}
+		sort.Strings(dimensions)
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Dimension;Value;Busy hour;Busy hour traffic (Erlangs);Growth (%);Projected traffic (Erlangs);Channels;Blocking (%)")
+
+		// Process and write the channels of each dimension and growth scenario to the output file.
+		for _, dimension := range dimensions {
+			// Find the busy hour, the first hour with the most traffic.
+			var busyHour time.Time
+			busyHourSeconds := float64(0)
+			for hour, seconds := range dimensionsHoursTraffic[dimension] {
+				if seconds > busyHourSeconds || (seconds == busyHourSeconds && hour.Before(busyHour)) {
+					busyHour = hour
+					busyHourSeconds = seconds
+				}
+			}
+			busyHourErlangs := busyHourSeconds / 3600
+
+			for _, growth := range growths {
+				projectedErlangs := busyHourErlangs * (1 + growth/100)
+				channels, blocking := erlangBChannels(projectedErlangs, targetBlocking)
+
+				// Write the formatted result to the output file.
+				fmt.Fprintf(outputFile, "%s;%s;%s;%s;%s;%d;%s\n", dimension, busyHour.Format("2006-01-02 15:04:05"), strconv.FormatFloat(busyHourErlangs, 'f', 2, 64), strconv.FormatFloat(growth, 'f', -1, 64), strconv.FormatFloat(projectedErlangs, 'f', 2, 64), channels, strconv.FormatFloat(blocking*100, 'f', 2, 64))
+			}
+		}
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestErlangBChannels(t *testing.T) {
+	tests := []struct {
+		erlangs        float64
+		targetBlocking float64
+		wantChannels   int
+		wantBlocking   float64
+	}{
+		{0, 0.01, 0, 0},
+		{-1, 0.01, 0, 0},
+		{1, 0.5, 1, 0.5},
+		{1, 0.01, 5, 0.0031},
+		{2, 0.01, 7, 0.0034},
+		{5, 0.01, 11, 0.0083},
+		{10, 0.01, 18, 0.0071},
+		{10, 0.001, 21, 0.0009},
+	}
+
+	for _, test := range tests {
+		channels, blocking := erlangBChannels(test.erlangs, test.targetBlocking)
+		if channels != test.wantChannels || math.Abs(blocking-test.wantBlocking) > 0.0001 {
+			t.Errorf("erlangBChannels(%g, %g) = %d, %.4f, want %d, %.4f", test.erlangs, test.targetBlocking, channels, blocking, test.wantChannels, test.wantBlocking)
+		}
+	}
+}
+
+func TestAddCallHoursTraffic(t *testing.T) {
+	hoursTraffic := make(map[time.Time]float64)
+	callStart := time.Date(2023, 1, 2, 9, 50, 0, 0, time.UTC)
+	addCallHoursTraffic(hoursTraffic, callStart, callStart.Add(80*time.Minute))
+
+	want := map[time.Time]float64{
+		time.Date(2023, 1, 2, 9, 0, 0, 0, time.UTC):  600,
+		time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC): 3600,
+		time.Date(2023, 1, 2, 11, 0, 0, 0, time.UTC): 600,
+	}
+	if len(hoursTraffic) != len(want) {
+		t.Fatalf("addCallHoursTraffic filled %d hours, want %d", len(hoursTraffic), len(want))
+	}
+	for hour, seconds := range want {
+		if hoursTraffic[hour] != seconds {
+			t.Errorf("traffic of %s = %g, want %g", hour.Format("15:04"), hoursTraffic[hour], seconds)
+		}
+	}
+}
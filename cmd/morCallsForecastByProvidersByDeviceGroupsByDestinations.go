@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsForecastByProvidersByDeviceGroupsByDestinations)
+	morCallsForecastByProvidersByDeviceGroupsByDestinations.Flags().StringP("dateStart", "s", "", "The start date of the history (default: 13 weeks before today)")
+	morCallsForecastByProvidersByDeviceGroupsByDestinations.Flags().StringP("dateEnd", "e", "", "The end date of the history (default: yesterday)")
+	morCallsForecastByProvidersByDeviceGroupsByDestinations.Flags().StringP("month", "m", "", "The forecast month, YYYY-MM (default: next month)")
+	morCallsForecastByProvidersByDeviceGroupsByDestinations.Flags().IntP("confidence", "c", 95, "The confidence level of the intervals in percent (80, 90, 95 or 99)")
+}
+
+// Normal quantiles of the confidence levels of the forecast intervals.
+var forecastConfidenceQuantiles = map[int]float64{
+	80: 1.2816,
+	90: 1.6449,
+	95: 1.9600,
+	99: 2.5758,
+}
+
+// Metrics of the forecast.
+var forecastMetrics = []string{"Calls", "Minutes", "Cost"}
+
+// ModelMorCallsForecastPerDays represents the answered calls of a day by provider, device group and destination.
+type ModelMorCallsForecastPerDays struct {
+	Day         string
+	Provider    string
+	DeviceGroup string
+	Destination string
+	Prefix      string
+	Calls       int
+	Billsec     int
+	Price       float64
+}
+
+// dailyForecast represents the forecast of a daily series over a period.
+type dailyForecast struct {
+	historyTotal float64
+	dailyTrend   float64
+	total        float64
+	lower        float64
+	upper        float64
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelMorCallsForecastPerDays(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCallsForecastPerDays
+
+		if err := rows.Scan(
+			&msg.Day,
+			&msg.Provider,
+			&msg.DeviceGroup,
+			&msg.Destination,
+			&msg.Prefix,
+			&msg.Calls,
+			&msg.Billsec,
+			&msg.Price,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Forecast the total of a daily series over the forecast days with a linear trend and a day of the week seasonality,
+// the interval is the total plus or minus the quantile times the standard deviation of the residuals of the sum of the days.
+func forecastDailySeries(values []float64, firstDay time.Time, forecastDays []time.Time, quantile float64) dailyForecast {
+	var forecast dailyForecast
+	n := float64(len(values))
+	for _, value := range values {
+		forecast.historyTotal += value
+	}
+	if len(values) < 2 {
+		return forecast
+	}
+
+	// Fit the linear trend and the average difference with the trend of each day of the week together,
+	// alternating the least squares trend of the values without seasonality and the seasonality of the values without trend.
+	var slope, intercept float64
+	var seasonality [7]float64
+	for iteration := 0; iteration < 20; iteration++ {
+		sumX, sumY, sumXY, sumXX := 0.0, 0.0, 0.0, 0.0
+		for i, value := range values {
+			x := float64(i)
+			y := value - seasonality[firstDay.AddDate(0, 0, i).Weekday()]
+			sumX += x
+			sumY += y
+			sumXY += x * y
+			sumXX += x * x
+		}
+		slope = (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+		intercept = (sumY - slope*sumX) / n
+
+		var seasonalityDays [7]int
+		seasonality = [7]float64{}
+		for i, value := range values {
+			weekday := firstDay.AddDate(0, 0, i).Weekday()
+			seasonality[weekday] += value - (intercept + slope*float64(i))
+			seasonalityDays[weekday]++
+		}
+		for weekday := range seasonality {
+			if seasonalityDays[weekday] > 0 {
+				seasonality[weekday] /= float64(seasonalityDays[weekday])
+			}
+		}
+	}
+	forecast.dailyTrend = slope
+
+	// Compute the standard deviation of the residuals.
+	squaredResiduals := 0.0
+	for i, value := range values {
+		residual := value - (intercept + slope*float64(i) + seasonality[firstDay.AddDate(0, 0, i).Weekday()])
+		squaredResiduals += residual * residual
+	}
+	standardDeviation := math.Sqrt(squaredResiduals / (n - 1))
+
+	// Project each forecast day, without negative values.
+	for _, day := range forecastDays {
+		x := math.Round(day.Sub(firstDay).Hours() / 24)
+		value := intercept + slope*x + seasonality[day.Weekday()]
+		if value > 0 {
+			forecast.total += value
+		}
+	}
+	margin := quantile * standardDeviation * math.Sqrt(float64(len(forecastDays)))
+	forecast.lower = math.Max(0, forecast.total-margin)
+	forecast.upper = forecast.total + margin
+
+	return forecast
+}
+
+// Define the main Cobra command for forecasting the traffic and the cost.
+var morCallsForecastByProvidersByDeviceGroupsByDestinations = &cobra.Command{
+	Use:   "morCallsForecastByProvidersByDeviceGroupsByDestinations",
+	Short: "Forecast the calls, minutes and cost of a month by providers, device groups and destinations from the daily history.",
+	Long: `Forecast the answered calls, the minutes and the provider cost of a month from the daily history of the MOR database, in total and by providers, device groups and destination countries, with a linear trend and a day of the week seasonality, and confidence intervals. The CSV will include the following columns: Dimension, Value, Metric, History days, History total, Daily trend, Forecast, Lower, Upper.
+
+Usage:
+  morCallsForecastByProvidersByDeviceGroupsByDestinations [-s [start_date] -e [end_date]] [-m [month]] [-c [confidence]]
+
+Flags:
+  -s, --dateStart string   The start date of the history (e.g., 'YYYY-MM-DD HH:mm:SS', default: 13 weeks before today)
+  -e, --dateEnd string     The end date of the history (e.g., 'YYYY-MM-DD HH:mm:SS', default: yesterday)
+  -m, --month string       The forecast month (e.g., 'YYYY-MM', default: next month)
+  -c, --confidence int     The confidence level of the intervals in percent: 80, 90, 95 or 99 (default 95)
+
+Example:
+  morCallsForecastByProvidersByDeviceGroupsByDestinations -s "2023-01-02 00:00:00" -e "2023-04-02 23:59:59" -m 2023-05 -c 90
+
+This command forecast the calls sent to the providers of the providersID list. Each daily series is fitted with a least squares trend and the average difference with the trend of each day of the week, the interval is based on the standard deviation of the residuals. Use whole weeks of history, at least 4 weeks for a meaningful seasonality. The Dimension column is Total, Provider, Device group or Country, the Daily trend column is the change per day of the daily value. The generated CSV file is named with a timestamp and saved in the current working directory. This forecast does not accept the --compare option: the date range is its history, the forecast month is the same for both periods.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings and the options from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+		monthStr, _ := cmd.Flags().GetString("month")
+		confidence, _ := cmd.Flags().GetInt("confidence")
+
+		// Use the last 13 weeks as history and forecast the next month when no dates are given.
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if dateStartStr == "" {
+			dateStartStr = today.AddDate(0, 0, -91).Format("2006-01-02 15:04:05")
+		}
+		if dateEndStr == "" {
+			dateEndStr = today.Add(-time.Second).Format("2006-01-02 15:04:05")
+		}
+		if monthStr == "" {
+			monthStr = time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC).Format("2006-01")
+		}
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Parse the forecast month and the confidence level.
+		month, err := time.Parse("2006-01", monthStr)
+		if err != nil {
+			fmt.Println("Invalid month format. Please use 'YYYY-MM'")
+			return
+		}
+		quantile, found := forecastConfidenceQuantiles[confidence]
+		if !found {
+			fmt.Println("Invalid confidence. Please use 80, 90, 95 or 99")
+			return
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morCallsForecastByProvidersByDeviceGroupsByDestinations called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + ", dateEnd: " + dateEnd.Format("2006-01-02 15:04:05") + " and month: " + month.Format("2006-01"))
+
+		// Build the list of the history days and of the forecast days.
+		firstDay := time.Date(dateStart.Year(), dateStart.Month(), dateStart.Day(), 0, 0, 0, 0, time.UTC)
+		historyDays := int(dateEnd.Sub(firstDay).Hours()/24) + 1
+		var forecastDays []time.Time
+		for day := month; day.Month() == month.Month(); day = day.AddDate(0, 0, 1) {
+			forecastDays = append(forecastDays, day)
+		}
+
+		// Build the device group SQL filter.
+		srcDevicesIDFilter, _ := getDeviceGroupFilter("c.src_device_id")
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			DATE(c.calldate) AS Day,
+			p.name AS Provider,
+			%s AS DeviceGroup,
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix,
+			count(*) AS Calls,
+			SUM(c.billsec) AS Billsec,
+			IFNULL(SUM(c.provider_price), 0) AS Price
+		FROM mor.calls c
+		INNER JOIN mor.providers p ON c.provider_id = p.id
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.provider_id IN (%s) AND
+			c.disposition = 'ANSWERED'
+		GROUP BY Day, Provider, DeviceGroup, Destination, Prefix;`, srcDevicesIDFilter, dateStartStr, dateEndStr, strings.Join(providersID, ","))
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCallsForecastPerDays)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Add the calls of each day to the daily series of each dimension and metric.
+		var dimensions []string
+		series := make(map[string]map[string][]float64)
+		countriesRegion := make(map[string]string)
+		for _, result := range results {
+			oneResult := result.(ModelMorCallsForecastPerDays)
+			day, err := time.Parse("2006-01-02", oneResult.Day)
+			if err != nil {
+				log.Fatal(err)
+			}
+			index := int(day.Sub(firstDay).Hours() / 24)
+			if index < 0 || index >= historyDays {
+				continue
+			}
+
+			// Retrieve the country of the destination once per prefix.
+			displayRegion, found := countriesRegion[oneResult.Prefix]
+			if !found {
+				_, displayRegion = getCountryFromPrefix(oneResult.Prefix, oneResult.Destination)
+				countriesRegion[oneResult.Prefix] = displayRegion
+			}
+
+			keys := []string{"Total;", "Provider;" + oneResult.Provider, "Country;" + displayRegion}
+			if oneResult.DeviceGroup != "" {
+				keys = append(keys, "Device group;"+oneResult.DeviceGroup)
+			}
+			for _, key := range keys {
+				if _, found := series[key]; !found {
+					series[key] = make(map[string][]float64)
+					for _, metric := range forecastMetrics {
+						series[key][metric] = make([]float64, historyDays)
+					}
+					dimensions = append(dimensions, key)
+				}
+				series[key]["Calls"][index] += float64(oneResult.Calls)
+				series[key]["Minutes"][index] += float64(oneResult.Billsec) / 60
+				series[key]["Cost"][index] += oneResult.Price
+			}
+		}
+		sort.Strings(dimensions)
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Dimension;Value;Metric;History days;History total;Daily trend;Forecast;Lower;Upper")
+
+		// Forecast and write each metric of each dimension to the output file.
+		for _, dimension := range dimensions {
+			for _, metric := range forecastMetrics {
+				forecast := forecastDailySeries(series[dimension][metric], firstDay, forecastDays, quantile)
+
+				// The costs are formatted as prices, the calls and minutes are rounded.
+				format := func(value float64) string {
+					if metric == "Cost" {
+						return formatPrice(value)
+					}
+					return strconv.FormatFloat(value, 'f', 0, 64)
+				}
+				fmt.Fprintf(outputFile, "%s;%s;%d;%s;%s;%s;%s;%s\n", dimension, metric, historyDays, format(forecast.historyTotal), strconv.FormatFloat(forecast.dailyTrend, 'f', 4, 64), format(forecast.total), format(forecast.lower), format(forecast.upper))
+			}
+		}
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
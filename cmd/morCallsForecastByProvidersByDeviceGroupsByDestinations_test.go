@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestForecastDailySeries(t *testing.T) {
+	// The history starts on Monday 2023-01-02, the forecast days follow the history.
+	firstDay := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	getDays := func(start int, count int) []time.Time {
+		var days []time.Time
+		for i := start; i < start+count; i++ {
+			days = append(days, firstDay.AddDate(0, 0, i))
+		}
+		return days
+	}
+	getSeries := func(count int, value func(i int) float64) []float64 {
+		var values []float64
+		for i := 0; i < count; i++ {
+			values = append(values, value(i))
+		}
+		return values
+	}
+
+	tests := []struct {
+		name         string
+		values       []float64
+		forecastDays []time.Time
+		want         dailyForecast
+	}{
+		{
+			name:         "constant series",
+			values:       getSeries(28, func(i int) float64 { return 10 }),
+			forecastDays: getDays(28, 7),
+			want:         dailyForecast{historyTotal: 280, dailyTrend: 0, total: 70, lower: 70, upper: 70},
+		},
+		{
+			name:         "linear series",
+			values:       getSeries(14, func(i int) float64 { return float64(i + 1) }),
+			forecastDays: getDays(14, 7),
+			want:         dailyForecast{historyTotal: 105, dailyTrend: 1, total: 126, lower: 126, upper: 126},
+		},
+		{
+			name: "day of the week seasonality",
+			values: getSeries(28, func(i int) float64 {
+				if i%7 >= 5 {
+					return 0
+				}
+				return 10
+			}),
+			forecastDays: getDays(28, 7),
+			want:         dailyForecast{historyTotal: 200, dailyTrend: 0, total: 50, lower: 50, upper: 50},
+		},
+		{
+			name:         "negative projection",
+			values:       []float64{10, 8, 6, 4, 2},
+			forecastDays: getDays(5, 3),
+			want:         dailyForecast{historyTotal: 30, dailyTrend: -2, total: 0, lower: 0, upper: 0},
+		},
+		{
+			name:         "single day of history",
+			values:       []float64{10},
+			forecastDays: getDays(1, 7),
+			want:         dailyForecast{historyTotal: 10},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := forecastDailySeries(test.values, firstDay, test.forecastDays, forecastConfidenceQuantiles[95])
+			for _, field := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"historyTotal", got.historyTotal, test.want.historyTotal},
+				{"dailyTrend", got.dailyTrend, test.want.dailyTrend},
+				{"total", got.total, test.want.total},
+				{"lower", got.lower, test.want.lower},
+				{"upper", got.upper, test.want.upper},
+			} {
+				if math.Abs(field.got-field.want) > 0.01 {
+					t.Errorf("%s = %f, want %f", field.name, field.got, field.want)
+				}
+			}
+		})
+	}
+}
+
+func TestForecastDailySeriesInterval(t *testing.T) {
+	firstDay := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	values := []float64{10, 12, 9, 11, 10, 13, 8, 10, 11, 9, 12, 10, 9, 11}
+	forecastDays := []time.Time{firstDay.AddDate(0, 0, 14), firstDay.AddDate(0, 0, 15)}
+
+	narrow := forecastDailySeries(values, firstDay, forecastDays, forecastConfidenceQuantiles[80])
+	wide := forecastDailySeries(values, firstDay, forecastDays, forecastConfidenceQuantiles[99])
+	if !(narrow.lower < narrow.total && narrow.total < narrow.upper) {
+		t.Errorf("the forecast %f is not inside its interval [%f, %f]", narrow.total, narrow.lower, narrow.upper)
+	}
+	if !(wide.lower < narrow.lower && narrow.upper < wide.upper) {
+		t.Errorf("the 99%% interval [%f, %f] is not wider than the 80%% interval [%f, %f]", wide.lower, wide.upper, narrow.lower, narrow.upper)
+	}
+}
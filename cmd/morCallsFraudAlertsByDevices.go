@@ -0,0 +1,464 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nyaruka/phonenumbers"
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsFraudAlertsByDevices)
+	morCallsFraudAlertsByDevices.Flags().StringP("dateStart", "s", "", "The start date of the scan (default: now minus the minutes)")
+	morCallsFraudAlertsByDevices.Flags().StringP("dateEnd", "e", "", "The end date of the scan (default: now)")
+	morCallsFraudAlertsByDevices.Flags().IntP("minutes", "m", 60, "The number of minutes scanned before now when no dates are given")
+	morCallsFraudAlertsByDevices.Flags().IntP("baselineDays", "b", 14, "The number of days before the scan used as baseline")
+	morCallsFraudAlertsByDevices.Flags().Float64P("spikeFactor", "f", 5, "The ratio between the scanned calls and the baseline triggering a spike alert")
+	morCallsFraudAlertsByDevices.Flags().IntP("minScore", "c", 1, "The minimum score of the exported devices")
+}
+
+// Scores and thresholds of the fraud rules.
+const (
+	fraudScoreSpike       = 30
+	fraudScoreHighRisk    = 40
+	fraudScoreOutOfHours  = 20
+	fraudScoreSequential  = 30
+	fraudScoreNewCountry  = 20
+	fraudSpikeMinCalls    = 10
+	fraudOutOfHoursCalls  = 5
+	fraudShortCallSeconds = 10
+	fraudSequentialCalls  = 5
+	fraudSequentialGap    = 10
+	fraudMaxAlertCalls    = 50
+)
+
+// ModelMorCallsFraudCall represents one outgoing call of a device.
+type ModelMorCallsFraudCall struct {
+	ID          string
+	CallDate    string
+	DeviceID    string
+	Device      string
+	Dst         string
+	Destination string
+	Prefix      string
+	Disposition string
+	Billsec     int
+	Price       float64
+}
+
+// ModelMorCallsFraudBaseline represents the baseline traffic of a device to a prefix.
+type ModelMorCallsFraudBaseline struct {
+	DeviceID    string
+	Destination string
+	Prefix      string
+	Calls       int
+	Price       float64
+}
+
+// ModelMorCallsFraudAlert represents a triggered rule with its triggering calls.
+type ModelMorCallsFraudAlert struct {
+	Rule   string
+	Score  int
+	Detail string
+	Calls  []ModelMorCallsFraudCall
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelMorCallsFraudCall(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCallsFraudCall
+
+		if err := rows.Scan(
+			&msg.ID,
+			&msg.CallDate,
+			&msg.DeviceID,
+			&msg.Device,
+			&msg.Dst,
+			&msg.Destination,
+			&msg.Prefix,
+			&msg.Disposition,
+			&msg.Billsec,
+			&msg.Price,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Retrieve baseline data from the database and return it as a slice of models.
+func getModelMorCallsFraudBaseline(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCallsFraudBaseline
+
+		if err := rows.Scan(
+			&msg.DeviceID,
+			&msg.Destination,
+			&msg.Prefix,
+			&msg.Calls,
+			&msg.Price,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Check if a normalized number starts with a high-risk prefix.
+func isHighRiskNumber(e164 string) bool {
+	for _, prefix := range highRiskPrefixes {
+		if strings.HasPrefix(e164, "+"+prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if a call date is outside the business hours.
+func isOutOfHours(callDate time.Time) bool {
+	return callDate.Weekday() == time.Saturday || callDate.Weekday() == time.Sunday || callDate.Hour() < businessHoursStart || callDate.Hour() >= businessHoursEnd
+}
+
+// Find the runs of short calls to sequential numbers.
+func getSequentialCalls(calls []ModelMorCallsFraudCall) []ModelMorCallsFraudCall {
+	// Keep the short calls to numeric destinations, sorted by destination.
+	var shortCalls []ModelMorCallsFraudCall
+	for _, call := range calls {
+		if call.Billsec < fraudShortCallSeconds {
+			if _, err := strconv.ParseUint(call.Dst, 10, 64); err == nil {
+				shortCalls = append(shortCalls, call)
+			}
+		}
+	}
+	sort.Slice(shortCalls, func(i, j int) bool {
+		if len(shortCalls[i].Dst) == len(shortCalls[j].Dst) {
+			return shortCalls[i].Dst < shortCalls[j].Dst
+		}
+		return len(shortCalls[i].Dst) < len(shortCalls[j].Dst)
+	})
+
+	// Keep the runs of at least fraudSequentialCalls different numbers with the same length and a small gap between them.
+	var sequentialCalls []ModelMorCallsFraudCall
+	runStart := 0
+	runNumbers := 1
+	for i := 1; i <= len(shortCalls); i++ {
+		inRun := false
+		if i < len(shortCalls) && len(shortCalls[i].Dst) == len(shortCalls[i-1].Dst) {
+			previous, _ := strconv.ParseUint(shortCalls[i-1].Dst, 10, 64)
+			current, _ := strconv.ParseUint(shortCalls[i].Dst, 10, 64)
+			inRun = current-previous <= fraudSequentialGap
+			if inRun && current != previous {
+				runNumbers++
+			}
+		}
+		if !inRun {
+			if runNumbers >= fraudSequentialCalls {
+				sequentialCalls = append(sequentialCalls, shortCalls[runStart:i]...)
+			}
+			runStart = i
+			runNumbers = 1
+		}
+	}
+
+	return sequentialCalls
+}
+
+// Limit the number of triggering calls of an alert.
+func limitFraudAlertCalls(calls []ModelMorCallsFraudCall) []ModelMorCallsFraudCall {
+	if len(calls) > fraudMaxAlertCalls {
+		return calls[:fraudMaxAlertCalls]
+	}
+	return calls
+}
+
+// Define the main Cobra command for exporting the fraud alerts.
+var morCallsFraudAlertsByDevices = &cobra.Command{
+	Use:   "morCallsFraudAlertsByDevices",
+	Short: "Export the scored toll fraud and anomaly alerts of the outgoing calls by devices.",
+	Long: `Scan the outgoing calls of the MOR database for toll fraud and anomalies by devices and export the scored alerts with their triggering calls. The CSV will include the following columns: Device ID, Device, Score, Rule, Rule score, Detail, Call ID, Call date, Dst, Country, Disposition, Billsec, Price.
+
+Usage:
+  morCallsFraudAlertsByDevices [-s [start_date] -e [end_date]] [-m [minutes]] [-b [baseline_days]] [-f [spike_factor]] [-c [min_score]]
+
+Flags:
+  -s, --dateStart string      The start date of the scan (e.g., 'YYYY-MM-DD HH:mm:SS', default: now minus the minutes)
+  -e, --dateEnd string        The end date of the scan (e.g., 'YYYY-MM-DD HH:mm:SS', default: now)
+  -m, --minutes int           The number of minutes scanned before now when no dates are given (default 60)
+  -b, --baselineDays int      The number of days before the scan used as baseline (default 14)
+  -f, --spikeFactor float     The ratio between the scanned calls and the baseline triggering a spike alert (default 5)
+  -c, --minScore int          The minimum score of the exported devices (default 1)
+
+Example:
+  morCallsFraudAlertsByDevices -m 15
+  morCallsFraudAlertsByDevices -s "2023-01-01 00:00:00" -e "2023-01-02 00:00:00"
+
+This command scan the outgoing calls of each source device with the following rules:
+  Spike (30): the calls or the price of the scan are more than spikeFactor times the baseline for the same duration (at least 10 calls).
+  High-risk destination (40): calls to premium rate numbers or to the prefixes of the highRiskPrefixes variable.
+  Out-of-hours (20): at least 5 calls outside the business hours (businessHoursStart and businessHoursEnd variables) or during the weekend.
+  Sequential numbers (30): calls shorter than 10 seconds to at least 5 different numbers following each other.
+  New country (20): calls to countries never called by the device during the baseline (the countries are found from the MOR prefix and destination of the calls).
+The score of a device is the sum of the scores of its triggered rules, at most 50 triggering calls are exported by rule. Run it every few minutes on a short window to detect a compromised extension. The generated CSV file is named with a timestamp and saved in the current working directory. This scan does not accept the --compare option: it already compares the scanned calls with the baseline days, and its rows are single calls.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the options from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+		minutes, _ := cmd.Flags().GetInt("minutes")
+		baselineDays, _ := cmd.Flags().GetInt("baselineDays")
+		spikeFactor, _ := cmd.Flags().GetFloat64("spikeFactor")
+		minScore, _ := cmd.Flags().GetInt("minScore")
+
+		// Scan the last minutes when no dates are given.
+		now := time.Now()
+		if dateStartStr == "" && dateEndStr == "" {
+			dateStartStr = now.Add(-time.Duration(minutes) * time.Minute).Format("2006-01-02 15:04:05")
+			dateEndStr = now.Format("2006-01-02 15:04:05")
+		}
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.ParseInLocation("2006-01-02 15:04:05", dateStartStr, time.Local)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.ParseInLocation("2006-01-02 15:04:05", dateEndStr, time.Local)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Check the scan and the baseline periods.
+		if !dateEnd.After(dateStart) || baselineDays <= 0 {
+			fmt.Println("Invalid period. Please use a dateEnd after the dateStart and a positive number of baseline days")
+			return
+		}
+		baselineStartStr := dateStart.AddDate(0, 0, -baselineDays).Format("2006-01-02 15:04:05")
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morCallsFraudAlertsByDevices called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05") + " and baseline since: " + baselineStartStr)
+
+		// Construct the SQL query of the scanned calls.
+		request := fmt.Sprintf(`SELECT
+			c.id AS ID,
+			c.calldate AS CallDate,
+			c.src_device_id AS DeviceID,
+			IFNULL(CONCAT(dv.extension, ' ', IFNULL(dv.description, '')), '') AS Device,
+			c.dst AS Dst,
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix,
+			c.disposition AS Disposition,
+			c.billsec AS Billsec,
+			IFNULL(c.provider_price, 0) AS Price
+		FROM mor.calls c
+		LEFT JOIN mor.devices dv ON c.src_device_id = dv.id
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.src_device_id > 0 AND
+			c.provider_id > 0
+		ORDER BY c.src_device_id, c.calldate;`, dateStartStr, dateEndStr)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCallsFraudCall)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Construct the SQL query of the baseline.
+		requestBaseline := fmt.Sprintf(`SELECT
+			c.src_device_id AS DeviceID,
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix,
+			count(*) AS Calls,
+			IFNULL(SUM(c.provider_price), 0) AS Price
+		FROM mor.calls c
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate <= '%s' AND
+			c.src_device_id > 0 AND
+			c.provider_id > 0
+		GROUP BY DeviceID, Destination, Prefix;`, baselineStartStr, dateStartStr)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(requestBaseline)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		resultsBaseline, err := MorRequest(requestBaseline, getModelMorCallsFraudBaseline)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Group the scanned calls by device.
+		var devicesID []string
+		devicesCalls := make(map[string][]ModelMorCallsFraudCall)
+		for _, result := range results {
+			call := result.(ModelMorCallsFraudCall)
+			if _, found := devicesCalls[call.DeviceID]; !found {
+				devicesID = append(devicesID, call.DeviceID)
+			}
+			devicesCalls[call.DeviceID] = append(devicesCalls[call.DeviceID], call)
+		}
+
+		// Sum the baseline calls and price and list the baseline countries by device.
+		baselineCalls := make(map[string]int)
+		baselinePrice := make(map[string]float64)
+		baselineCountries := make(map[string]map[string]bool)
+		for _, result := range resultsBaseline {
+			baseline := result.(ModelMorCallsFraudBaseline)
+			baselineCalls[baseline.DeviceID] += baseline.Calls
+			baselinePrice[baseline.DeviceID] += baseline.Price
+			if baselineCountries[baseline.DeviceID] == nil {
+				baselineCountries[baseline.DeviceID] = make(map[string]bool)
+			}
+			_, displayRegion := getCountryFromPrefix(baseline.Prefix, baseline.Destination)
+			baselineCountries[baseline.DeviceID][displayRegion] = true
+		}
+
+		// The baseline is scaled to the duration of the scan.
+		baselineScale := dateEnd.Sub(dateStart).Hours() / float64(baselineDays*24)
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Device ID;Device;Score;Rule;Rule score;Detail;Call ID;Call date;Dst;Country;Disposition;Billsec;Price")
+
+		// Apply the rules to the calls of each device.
+		alertedDevices := 0
+		for _, deviceID := range devicesID {
+			calls := devicesCalls[deviceID]
+			var alerts []ModelMorCallsFraudAlert
+
+			// Normalize the destination of each call, the country is found from the prefix and the destination like the baseline countries.
+			callsCountry := make(map[string]string)
+			var highRiskCalls []ModelMorCallsFraudCall
+			var outOfHoursCalls []ModelMorCallsFraudCall
+			newCountriesCalls := make(map[string][]ModelMorCallsFraudCall)
+			var newCountries []string
+			price := float64(0)
+			for _, call := range calls {
+				price += call.Price
+
+				_, callsCountry[call.ID] = getCountryFromPrefix(call.Prefix, call.Destination)
+				e164, _, numberType, err := getNumberInformation(call.Dst)
+				if err == nil {
+					if numberType == phonenumbers.PREMIUM_RATE || isHighRiskNumber(e164) {
+						highRiskCalls = append(highRiskCalls, call)
+					}
+				}
+
+				callDate, err := time.ParseInLocation("2006-01-02 15:04:05", call.CallDate, time.Local)
+				if err == nil && isOutOfHours(callDate) {
+					outOfHoursCalls = append(outOfHoursCalls, call)
+				}
+
+				country := callsCountry[call.ID]
+				if baselineCalls[deviceID] > 0 && country != "UNKNOWN" && !baselineCountries[deviceID][country] {
+					if _, found := newCountriesCalls[country]; !found {
+						newCountries = append(newCountries, country)
+					}
+					newCountriesCalls[country] = append(newCountriesCalls[country], call)
+				}
+			}
+
+			// Spike of calls or price against the baseline.
+			expectedCalls := float64(baselineCalls[deviceID]) * baselineScale
+			expectedPrice := baselinePrice[deviceID] * baselineScale
+			if len(calls) >= fraudSpikeMinCalls && (float64(len(calls)) > spikeFactor*expectedCalls || (price > 0 && price > spikeFactor*expectedPrice)) {
+				alerts = append(alerts, ModelMorCallsFraudAlert{Rule: "Spike", Score: fraudScoreSpike, Detail: fmt.Sprintf("%d calls (baseline %s) for %s (baseline %s)", len(calls), strconv.FormatFloat(expectedCalls, 'f', 2, 64), formatPrice(price), formatPrice(expectedPrice)), Calls: calls})
+			}
+
+			// Calls to high-risk destinations.
+			if len(highRiskCalls) > 0 {
+				alerts = append(alerts, ModelMorCallsFraudAlert{Rule: "High-risk destination", Score: fraudScoreHighRisk, Detail: fmt.Sprintf("%d calls to high-risk destinations", len(highRiskCalls)), Calls: highRiskCalls})
+			}
+
+			// Bursts of calls out of the business hours.
+			if len(outOfHoursCalls) >= fraudOutOfHoursCalls {
+				alerts = append(alerts, ModelMorCallsFraudAlert{Rule: "Out-of-hours", Score: fraudScoreOutOfHours, Detail: fmt.Sprintf("%d calls out of the business hours", len(outOfHoursCalls)), Calls: outOfHoursCalls})
+			}
+
+			// Short calls to sequential numbers.
+			sequentialCalls := getSequentialCalls(calls)
+			if len(sequentialCalls) > 0 {
+				alerts = append(alerts, ModelMorCallsFraudAlert{Rule: "Sequential numbers", Score: fraudScoreSequential, Detail: fmt.Sprintf("%d short calls to sequential numbers", len(sequentialCalls)), Calls: sequentialCalls})
+			}
+
+			// Calls to new countries.
+			if len(newCountries) > 0 {
+				var newCountryCalls []ModelMorCallsFraudCall
+				for _, country := range newCountries {
+					newCountryCalls = append(newCountryCalls, newCountriesCalls[country]...)
+				}
+				alerts = append(alerts, ModelMorCallsFraudAlert{Rule: "New country", Score: fraudScoreNewCountry, Detail: "New countries: " + strings.Join(newCountries, ", "), Calls: newCountryCalls})
+			}
+
+			// Sum the score of the device and skip it under the minimum score.
+			score := 0
+			for _, alert := range alerts {
+				score += alert.Score
+			}
+			if score == 0 || score < minScore {
+				continue
+			}
+			alertedDevices++
+
+			// Write the alerts with their triggering calls to the output file.
+			for _, alert := range alerts {
+				for _, call := range limitFraudAlertCalls(alert.Calls) {
+					fmt.Fprintf(outputFile, "%s;%s;%d;%s;%d;%s;%s;%s;%s;%s;%s;%d;%s\n", deviceID, call.Device, score, alert.Rule, alert.Score, alert.Detail, call.ID, call.CallDate, call.Dst, callsCountry[call.ID], call.Disposition, call.Billsec, formatPrice(call.Price))
+				}
+			}
+		}
+
+		// Display the number of alerted devices.
+		fmt.Printf("%d devices with alerts\n", alertedDevices)
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
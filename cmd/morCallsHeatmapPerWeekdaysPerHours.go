@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsHeatmapPerWeekdaysPerHours)
+	registerReport(morCallsHeatmapPerWeekdaysPerHours, "Group;Direction;Metric;Day", "Group", "Direction", "Metric", "Day")
+	morCallsHeatmapPerWeekdaysPerHours.Flags().StringP("dateStart", "s", "", "The start date of the export")
+	morCallsHeatmapPerWeekdaysPerHours.Flags().StringP("dateEnd", "e", "", "The end date of the export")
+	morCallsHeatmapPerWeekdaysPerHours.Flags().StringP("direction", "d", "all", "The direction of the calls (incoming, outgoing, local or all)")
+	morCallsHeatmapPerWeekdaysPerHours.Flags().StringP("groupBy", "g", "", "Split the heatmap by deviceGroup or provider")
+}
+
+// Names of the days of the week, in the order of the MySQL WEEKDAY function (0 is Monday).
+var heatmapWeekdays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// ModelMorCallsHeatmapPerWeekdaysPerHours represents the calls of an hour of a day of the week.
+type ModelMorCallsHeatmapPerWeekdaysPerHours struct {
+	Group     string
+	Direction string
+	Weekday   int
+	Hour      int
+	Attempts  int
+	Answered  int
+	Billsec   int
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelMorCallsHeatmapPerWeekdaysPerHours(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCallsHeatmapPerWeekdaysPerHours
+
+		if err := rows.Scan(
+			&msg.Group,
+			&msg.Direction,
+			&msg.Weekday,
+			&msg.Hour,
+			&msg.Attempts,
+			&msg.Answered,
+			&msg.Billsec,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Define the main Cobra command for exporting the traffic heatmap.
+var morCallsHeatmapPerWeekdaysPerHours = &cobra.Command{
+	Use:   "morCallsHeatmapPerWeekdaysPerHours",
+	Short: "Export the day of the week by hour of the day matrix of the calls attempts, answered calls and minutes.",
+	Long: `Export the traffic heatmap of the calls from the MOR database: a day of the week by hour of the day matrix of the calls attempts, answered calls and minutes, per direction and optionally per device group or provider. The CSV will include the following columns: Group, Direction, Metric, Day, 00 to 23, Total.
+
+Usage:
+  morCallsHeatmapPerWeekdaysPerHours -s [start_date] -e [end_date] [-d [direction]] [-g [group_by]]
+
+Flags:
+  -s, --dateStart string   The start date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string     The end date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -d, --direction string   The direction of the calls: incoming, outgoing, local or all (default "all")
+  -g, --groupBy string     Split the heatmap by deviceGroup or provider
+
+Example:
+  morCallsHeatmapPerWeekdaysPerHours -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59" -d outgoing -g deviceGroup
+
+This command export one matrix per group, direction and metric (Attempts, Answered, Minutes) with a row per day of the week and a column per hour of the day, from the start date of the calls. The minutes are the billed seconds divided by 60 with 2 decimals. The incoming calls are the calls received on a DID, even when they are forwarded to a provider, the outgoing calls are the other calls sent to a provider. The generated CSV file is named with a timestamp and saved in the current working directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings and the options from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+		direction, _ := cmd.Flags().GetString("direction")
+		groupBy, _ := cmd.Flags().GetString("groupBy")
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Build the direction filter, the filtered directions are split in the heatmap.
+		directionFilter, err := getDirectionFilter(direction)
+		if err != nil {
+			fmt.Println("Invalid direction. Please use incoming, outgoing, local or all")
+			return
+		}
+
+		// Build the group expression.
+		groupExpression := "''"
+		switch groupBy {
+		case "":
+		case "deviceGroup":
+			groupExpression, _ = getDeviceGroupFilter("c.src_device_id")
+		case "provider":
+			groupExpression = "IFNULL(p.name, '')"
+		default:
+			fmt.Println("Invalid groupBy. Please use deviceGroup or provider")
+			return
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morCallsHeatmapPerWeekdaysPerHours called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			%s AS GroupName,
+			%s AS Direction,
+			WEEKDAY(c.calldate) AS Weekday,
+			HOUR(c.calldate) AS Hour,
+			count(*) AS Attempts,
+			SUM(CASE WHEN c.disposition = 'ANSWERED' THEN 1 ELSE 0 END) AS Answered,
+			SUM(CASE WHEN c.disposition = 'ANSWERED' THEN c.billsec ELSE 0 END) AS Billsec
+		FROM mor.calls c
+		LEFT JOIN mor.providers p ON c.provider_id = p.id
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			%s
+		GROUP BY GroupName, Direction, Weekday, Hour
+		ORDER BY GroupName, Direction, Weekday, Hour;`, groupExpression, directionExpression, dateStartStr, dateEndStr, directionFilter)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCallsHeatmapPerWeekdaysPerHours)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Fill the matrices of each group and direction.
+		var matricesKeys []string
+		attempts := make(map[string]*[7][24]int)
+		answered := make(map[string]*[7][24]int)
+		billsec := make(map[string]*[7][24]int)
+		for _, result := range results {
+			oneResult := result.(ModelMorCallsHeatmapPerWeekdaysPerHours)
+			key := oneResult.Group + ";" + oneResult.Direction
+			if _, found := attempts[key]; !found {
+				matricesKeys = append(matricesKeys, key)
+				attempts[key] = &[7][24]int{}
+				answered[key] = &[7][24]int{}
+				billsec[key] = &[7][24]int{}
+			}
+			attempts[key][oneResult.Weekday][oneResult.Hour] += oneResult.Attempts
+			answered[key][oneResult.Weekday][oneResult.Hour] += oneResult.Answered
+			billsec[key][oneResult.Weekday][oneResult.Hour] += oneResult.Billsec
+		}
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		var hours []string
+		for hour := 0; hour < 24; hour++ {
+			hours = append(hours, fmt.Sprintf("%02d", hour))
+		}
+		fmt.Fprintln(outputFile, "Group;Direction;Metric;Day;"+strings.Join(hours, ";")+";Total")
+
+		// Write each matrix, one row per day of the week.
+		for _, key := range matricesKeys {
+			for _, metric := range []string{"Attempts", "Answered", "Minutes"} {
+				for weekday, weekdayName := range heatmapWeekdays {
+					var values []string
+					total := 0
+					for hour := 0; hour < 24; hour++ {
+						value := attempts[key][weekday][hour]
+						if metric == "Answered" {
+							value = answered[key][weekday][hour]
+						} else if metric == "Minutes" {
+							value = billsec[key][weekday][hour]
+						}
+						total += value
+
+						// The minutes are decimal, so that the total is the sum of the hours.
+						if metric == "Minutes" {
+							values = append(values, strconv.FormatFloat(float64(value)/60, 'f', 2, 64))
+						} else {
+							values = append(values, strconv.Itoa(value))
+						}
+					}
+					formattedTotal := strconv.Itoa(total)
+					if metric == "Minutes" {
+						formattedTotal = strconv.FormatFloat(float64(total)/60, 'f', 2, 64)
+					}
+					fmt.Fprintf(outputFile, "%s;%s;%s;%s;%s\n", key, metric, weekdayName, strings.Join(values, ";"), formattedTotal)
+				}
+			}
+		}
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
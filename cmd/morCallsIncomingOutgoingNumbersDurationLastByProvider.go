@@ -13,6 +13,7 @@ import (
 // Initialize the command.
 func init() {
 	rootCmd.AddCommand(morCallsIncomingOutgoingNumbersDurationLastByProvider)
+	registerReport(morCallsIncomingOutgoingNumbersDurationLastByProvider, "DID;Incoming Calls", "DID")
 	morCallsIncomingOutgoingNumbersDurationLastByProvider.Flags().StringP("provider", "p", "", "A part of the provider name of the export")
 	morCallsIncomingOutgoingNumbersDurationLastByProvider.Flags().StringP("dateStart", "s", "", "The start date of the export")
 	morCallsIncomingOutgoingNumbersDurationLastByProvider.Flags().StringP("dateEnd", "e", "", "The end date of the export")
@@ -83,8 +84,7 @@ Example:
 Export incoming and outgoing calls data for actives numbers (calls numbers, duration,last call date) for a specified date range and provider. The CSV will include the following columns: DID, Incoming Calls, Incoming Duration (seconds), Last Incoming, Outgoing Calls, Outgoing Duration (seconds), Last Outgoing, Provider. The generated CSV file is named with a timestamp and saved in the current working directory.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Obtain the start and end date strings from the command-line flags.
-		dateStartStr, _ := cmd.Flags().GetString("dateStart")
-		dateEndStr, _ := cmd.Flags().GetString("dateEnd")
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
 		provider := cmd.Flag("provider").Value.String()
 
 		// Parse the provided start and end dates.
@@ -145,8 +145,7 @@ Export incoming and outgoing calls data for actives numbers (calls numbers, dura
 		}
 
 		// Generate a filename for the output file.
-		now := time.Now()
-		filename := fmt.Sprintf("%d_%02d_%02d_%02d_%02d_%02d_export.csv", now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second())
+		filename := getExportFilename(cmd)
 
 		// Create and open the output file for writing.
 		outputFile, err := os.Create(filename)
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsLeastCostRoutingByDestinationsByProviders)
+	registerReport(morCallsLeastCostRoutingByDestinationsByProviders, "Country;Destination;Prefix;Provider", "Prefix", "Provider")
+	morCallsLeastCostRoutingByDestinationsByProviders.Flags().StringP("dateStart", "s", "", "The start date of the analysis")
+	morCallsLeastCostRoutingByDestinationsByProviders.Flags().StringP("dateEnd", "e", "", "The end date of the analysis")
+}
+
+// ModelMorCallsLeastCostRoutingCall represents an answered call sent to a provider with its price.
+type ModelMorCallsLeastCostRoutingCall struct {
+	CallDate    string
+	ProviderID  string
+	Destination string
+	Prefix      string
+	Billsec     int
+	Price       float64
+	Dst         string
+}
+
+// leastCostRoutingRow represents the calls of a destination carried by a provider, with their cost on each provider.
+type leastCostRoutingRow struct {
+	country        string
+	destination    string
+	prefix         string
+	provider       string
+	calls          int
+	billsec        int
+	actualCost     float64
+	providersCost  map[string]float64
+	providersCalls map[string]int
+	cheapest       string
+	savings        float64
+}
+
+// Retrieve call data from the database and return it as a model.
+func getModelMorCallsLeastCostRoutingCall(rows *sql.Rows) (ModelMorCallsLeastCostRoutingCall, error) {
+	var msg ModelMorCallsLeastCostRoutingCall
+
+	err := rows.Scan(
+		&msg.CallDate,
+		&msg.ProviderID,
+		&msg.Destination,
+		&msg.Prefix,
+		&msg.Billsec,
+		&msg.Price,
+		&msg.Dst,
+	)
+
+	return msg, err
+}
+
+// Define the main Cobra command for analysing the least-cost routing.
+var morCallsLeastCostRoutingByDestinationsByProviders = &cobra.Command{
+	Use:   "morCallsLeastCostRoutingByDestinationsByProviders",
+	Short: "Export the cost of the calls by destinations and providers compared with the cheapest provider of the rate decks.",
+	Long: `Re-rate the answered calls sent to the providers of the providersID list with the rate decks of all these providers, and export by destination and carrying provider the actual cost, the cheapest provider able to carry all the calls, its cost and the potential savings. The CSV will include the following columns: Country, Destination, Prefix, Provider, Calls, Minutes, Actual cost, Cheapest provider, Cheapest cost, Savings, Savings (%), Rated providers.
+
+Usage:
+  morCallsLeastCostRoutingByDestinationsByProviders -s [start_date] -e [end_date]
+
+Flags:
+  -s, --dateStart string   The start date of the analysis (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string     The end date of the analysis (e.g., 'YYYY-MM-DD HH:mm:SS')
+
+Example:
+  morCallsLeastCostRoutingByDestinationsByProviders -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59"
+
+This command use the tariff of each provider in the MOR database, with the increments, minimum times and connection fees, and the actual cost is the provider price of the calls. The Rated providers column is the number of providers with a rate for all the calls of the row. The rows are sorted by savings. The generated CSV file is named with a timestamp and saved in the current working directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morCallsLeastCostRoutingByDestinationsByProviders called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Load the providers with their tariff and the rate decks of the tariffs.
+		providers, err := getMorProvidersTariffs()
+		if err != nil {
+			log.Fatal(err)
+		}
+		rateDecks, err := getMorRateDecks()
+		if err != nil {
+			log.Fatal(err)
+		}
+		providersName := make(map[string]string)
+		for _, provider := range providers {
+			providersName[provider.ID] = provider.Name
+		}
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			c.calldate AS CallDate,
+			c.provider_id AS ProviderID,
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix,
+			c.billsec AS Billsec,
+			IFNULL(c.provider_price, 0) AS Price,
+			IFNULL(c.dst, '') AS Dst
+		FROM mor.calls c
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.provider_id IN (%s) AND
+			c.disposition = 'ANSWERED';`, dateStartStr, dateEndStr, strings.Join(providersID, ","))
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and re-rate each call on each provider while it is read.
+		var rowsKeys []string
+		lcrRows := make(map[string]*leastCostRoutingRow)
+		countriesRegion := make(map[string]string)
+		_, err = MorRequest(request, func(stmt *sql.Stmt) ([]any, error) {
+			rows, err := stmt.Query()
+			if err != nil {
+				return nil, err
+			}
+
+			defer rows.Close()
+
+			for rows.Next() {
+				call, err := getModelMorCallsLeastCostRoutingCall(rows)
+				if err != nil {
+					return nil, err
+				}
+				callDate, err := time.Parse("2006-01-02 15:04:05", call.CallDate)
+				if err != nil {
+					return nil, err
+				}
+
+				// Retrieve the row of the destination and of the carrying provider.
+				key := call.Prefix + ";" + call.ProviderID
+				row, found := lcrRows[key]
+				if !found {
+					country, found := countriesRegion[call.Prefix]
+					if !found {
+						_, country = getCountryFromPrefix(call.Prefix, call.Destination)
+						countriesRegion[call.Prefix] = country
+					}
+					row = &leastCostRoutingRow{country: country, destination: call.Destination, prefix: call.Prefix, provider: providersName[call.ProviderID], providersCost: make(map[string]float64), providersCalls: make(map[string]int)}
+					lcrRows[key] = row
+					rowsKeys = append(rowsKeys, key)
+				}
+				row.calls++
+				row.billsec += call.Billsec
+				row.actualCost += call.Price
+
+				// Compute the cost of the call on each provider with a rate for its prefix.
+				for _, provider := range providers {
+					deck, found := rateDecks[provider.TariffID]
+					if !found {
+						continue
+					}
+					rate, found := deck.findRate(call.Prefix, callDate)
+					if !found {
+						continue
+					}
+					row.providersCost[provider.Name] += computeCallPrice(rate, call.Billsec)
+					row.providersCalls[provider.Name]++
+				}
+			}
+
+			return nil, rows.Err()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Find the cheapest provider able to carry all the calls of each row.
+		totalActualCost := float64(0)
+		totalSavings := float64(0)
+		for _, key := range rowsKeys {
+			row := lcrRows[key]
+			for _, provider := range providers {
+				if row.providersCalls[provider.Name] != row.calls {
+					continue
+				}
+				if row.cheapest == "" || row.providersCost[provider.Name] < row.providersCost[row.cheapest] {
+					row.cheapest = provider.Name
+				}
+			}
+			if row.cheapest != "" {
+				row.savings = row.actualCost - row.providersCost[row.cheapest]
+			}
+			totalActualCost += row.actualCost
+			totalSavings += row.savings
+		}
+		sort.SliceStable(rowsKeys, func(i, j int) bool { return lcrRows[rowsKeys[i]].savings > lcrRows[rowsKeys[j]].savings })
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Country;Destination;Prefix;Provider;Calls;Minutes;Actual cost;Cheapest provider;Cheapest cost;Savings;Savings (%);Rated providers")
+
+		// Process and write each row to the output file.
+		for _, key := range rowsKeys {
+			row := lcrRows[key]
+
+			// Count the providers with a rate for all the calls of the row.
+			ratedProviders := 0
+			for _, calls := range row.providersCalls {
+				if calls == row.calls {
+					ratedProviders++
+				}
+			}
+
+			// The cheapest cost and the savings are empty when no provider has a rate for all the calls.
+			cheapestCost := ""
+			savings := ""
+			savingsPercent := ""
+			if row.cheapest != "" {
+				cheapestCost = formatPrice(row.providersCost[row.cheapest])
+				savings = formatPrice(row.savings)
+				if row.actualCost != 0 {
+					savingsPercent = strconv.FormatFloat(row.savings*100/row.actualCost, 'f', 2, 64)
+				}
+			}
+
+			// Write the formatted result to the output file.
+			fmt.Fprintf(outputFile, "%s;%s;%s;%s;%d;%s;%s;%s;%s;%s;%s;%d\n", row.country, row.destination, row.prefix, row.provider, row.calls, strconv.FormatFloat(float64(row.billsec)/60, 'f', 2, 64), formatPrice(row.actualCost), row.cheapest, cheapestCost, savings, savingsPercent, ratedProviders)
+		}
+
+		// Display the total potential savings.
+		fmt.Printf("Actual cost %s, potential savings %s\n", formatPrice(totalActualCost), formatPrice(totalSavings))
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
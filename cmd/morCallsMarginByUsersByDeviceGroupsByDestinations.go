@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsMarginByUsersByDeviceGroupsByDestinations)
+	registerReport(morCallsMarginByUsersByDeviceGroupsByDestinations, "Reseller;User;Device group", "Reseller", "User", "Device group", "Country", "Destination", "Prefix")
+	morCallsMarginByUsersByDeviceGroupsByDestinations.Flags().StringP("dateStart", "s", "", "The start date of the export")
+	morCallsMarginByUsersByDeviceGroupsByDestinations.Flags().StringP("dateEnd", "e", "", "The end date of the export")
+}
+
+// ModelMorCallsMarginByUsersByDeviceGroupsByDestinations represents the revenue and the cost of the calls of a user to a destination.
+type ModelMorCallsMarginByUsersByDeviceGroupsByDestinations struct {
+	Reseller    string
+	User        string
+	DeviceGroup string
+	Destination string
+	Prefix      string
+	Calls       int
+	Duration    int
+	UserPrice   float64
+	Revenue     float64
+	Cost        float64
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelMorCallsMarginByUsersByDeviceGroupsByDestinations(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCallsMarginByUsersByDeviceGroupsByDestinations
+
+		if err := rows.Scan(
+			&msg.Reseller,
+			&msg.User,
+			&msg.DeviceGroup,
+			&msg.Destination,
+			&msg.Prefix,
+			&msg.Calls,
+			&msg.Duration,
+			&msg.UserPrice,
+			&msg.Revenue,
+			&msg.Cost,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Define the main Cobra command for exporting the margins.
+var morCallsMarginByUsersByDeviceGroupsByDestinations = &cobra.Command{
+	Use:   "morCallsMarginByUsersByDeviceGroupsByDestinations",
+	Short: "Export the revenue, the cost and the margin of the answered outgoing calls by users, resellers, device groups and destinations.",
+	Long: `Export the revenue, the cost and the margin of the answered outgoing calls from MOR database, grouped by resellers, users, device groups and destinations. The CSV will include the following columns: Reseller, User, Device group, Country, Destination, Prefix, Calls, Duration (seconds), User price, Revenue, Cost, Margin, Margin (%), Loss.
+
+Usage:
+  morCallsMarginByUsersByDeviceGroupsByDestinations -s [start_date] -e [end_date]
+
+Flags:
+  -s, --dateStart string   The start date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string     The end date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+
+Example:
+  morCallsMarginByUsersByDeviceGroupsByDestinations -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59"
+
+This command export the margin of the answered outgoing calls. The user price is what the user is charged, the revenue is what we charge: the reseller price when the user belongs to a reseller, the user price otherwise. The cost is the provider price, the margin is the revenue minus the cost and the Loss column is set to YES for the destinations sold at a loss. The generated CSV file is named with a timestamp and saved in the current working directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morCallsMarginByUsersByDeviceGroupsByDestinations called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Build the device group SQL filter.
+		srcDevicesIDFilter, _ := getDeviceGroupFilter("c.src_device_id")
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			IFNULL(r.username, '') AS Reseller,
+			IFNULL(u.username, '') AS User,
+			%s AS DeviceGroup,
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix,
+			count(*) AS Calls,
+			SUM(c.billsec) AS Duration,
+			IFNULL(SUM(c.user_price), 0) AS UserPrice,
+			IFNULL(SUM(IF(c.reseller_id > 0, c.reseller_price, c.user_price)), 0) AS Revenue,
+			IFNULL(SUM(c.provider_price), 0) AS Cost
+		FROM mor.calls c
+		LEFT JOIN mor.users u ON c.user_id = u.id
+		LEFT JOIN mor.users r ON c.reseller_id = r.id
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.provider_id > 0 AND
+			c.disposition = 'ANSWERED'
+		GROUP BY Reseller, User, DeviceGroup, Destination, Prefix
+		ORDER BY Reseller, User, DeviceGroup, Destination;`, srcDevicesIDFilter, dateStartStr, dateEndStr)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCallsMarginByUsersByDeviceGroupsByDestinations)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Create a list to hold the results casted to the desired data model.
+		var resultsCasted []ModelMorCallsMarginByUsersByDeviceGroupsByDestinations
+		for _, result := range results {
+			resultsCasted = append(resultsCasted, result.(ModelMorCallsMarginByUsersByDeviceGroupsByDestinations))
+		}
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Reseller;User;Device group;Country;Destination;Prefix;Calls;Duration (seconds);User price;Revenue;Cost;Margin;Margin (%);Loss")
+
+		// Process and write each result to the output file.
+		for _, oneResult := range resultsCasted {
+			// Retrieve the formatted prefix and the country of the destination.
+			formattedPrefix, displayRegion := getCountryFromPrefix(oneResult.Prefix, oneResult.Destination)
+
+			// Calculate the margin and the margin percent of the revenue.
+			margin := oneResult.Revenue - oneResult.Cost
+			marginPercent := float64(0)
+			if oneResult.Revenue != 0 {
+				marginPercent = margin * 100 / oneResult.Revenue
+			}
+
+			// Flag the destinations sold at a loss.
+			loss := ""
+			if margin < 0 {
+				loss = "YES"
+			}
+
+			// Write the formatted result to the output file.
+			fmt.Fprintf(outputFile, "%s;%s;%s;%s;%s;%s;%d;%d;%s;%s;%s;%s;%s;%s\n", oneResult.Reseller, oneResult.User, oneResult.DeviceGroup, displayRegion, oneResult.Destination, formattedPrefix, oneResult.Calls, oneResult.Duration, formatPrice(oneResult.UserPrice), formatPrice(oneResult.Revenue), formatPrice(oneResult.Cost), formatPrice(margin), strconv.FormatFloat(marginPercent, 'f', 2, 64), loss)
+		}
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
@@ -5,17 +5,16 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 
-	"github.com/nyaruka/phonenumbers"
-	"github.com/pariz/gountries"
 	"github.com/spf13/cobra"
 )
 
 // Initialize the command.
 func init() {
 	rootCmd.AddCommand(morMaxCallsNumberPerDaysByDestinations)
+	registerReport(morMaxCallsNumberPerDaysByDestinations, "Day;Country;Calls", "Day", "Country")
+	registerIncremental(morMaxCallsNumberPerDaysByDestinations, true)
 	morMaxCallsNumberPerDaysByDestinations.Flags().StringP("dateStart", "s", "", "The start date of the export")
 	morMaxCallsNumberPerDaysByDestinations.Flags().StringP("dateEnd", "e", "", "The end date of the export")
 }
@@ -83,8 +82,7 @@ morMaxCallsNumberPerDaysByDestinations -s "2023-01-01 00:00:00" -e "2023-01-31 2
 This command export the maximum numbers of calls for each destinations per days from the MOR database. It generates a CSV file with the specified start and end date. The CSV file contains information about day, country, calls. The generated CSV file is named with a timestamp and saved in the current working directory.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Obtain the start and end date strings from the command-line flags.
-		dateStartStr, _ := cmd.Flags().GetString("dateStart")
-		dateEndStr, _ := cmd.Flags().GetString("dateEnd")
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
 
 		// Parse the provided start and end dates.
 		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
@@ -132,8 +130,7 @@ This command export the maximum numbers of calls for each destinations per days
 		}
 
 		// Generate a filename for the output file.
-		now := time.Now()
-		filename := fmt.Sprintf("%d_%02d_%02d_%02d_%02d_%02d_export.csv", now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second())
+		filename := getExportFilename(cmd)
 
 		// Create and open the output file for writing.
 		outputFile, err := os.Create(filename)
@@ -150,80 +147,21 @@ This command export the maximum numbers of calls for each destinations per days
 
 		// Process and write each result to the output file.
 		for _, oneResult := range resultsCasted {
-			// Process and format the prefix for phone numbers.
-			oneResult.Prefix = "+" + oneResult.Prefix
-			oneResult.Prefix = strings.TrimRight(oneResult.Prefix+"000000", " ")[0:6]
-
-			// Parse and format phone number information.
-			phoneNumber, err := phonenumbers.Parse(oneResult.Prefix, "")
-			if err == nil {
-				// Retrieve the region (country) code associated with the phone number.
-				regionCode := phonenumbers.GetRegionCodeForNumber(phoneNumber)
-
-				// Initialize a new query object for the gountries library, which will be used to fetch additional country information.
-				query := gountries.New()
-
-				// Find the country information based on the region code.
-				displayRegionQuery, _ := query.FindCountryByAlpha(regionCode)
-				displayRegion := displayRegionQuery.Name.Common
-
-				if displayRegion == "" {
-					// If the region is not found, provide fallback information for certain countries.
-					if strings.Contains(strings.ToLower(oneResult.Destination), "australia") || strings.Contains(strings.ToLower(oneResult.Destination), "australie") {
-						displayRegion = "Australia"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "canada") {
-						displayRegion = "Canada"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "italy") {
-						displayRegion = "Italy"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "russia") {
-						displayRegion = "Russia"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "unites states") {
-						displayRegion = "United States"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "guadeloupe") {
-						displayRegion = "France"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "morocco") {
-						displayRegion = "Morocco"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "reunion") || strings.Contains(strings.ToLower(oneResult.Destination), "r?union") || strings.Contains(strings.ToLower(oneResult.Destination), "france") {
-						displayRegion = "France"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "uk") || strings.Contains(strings.ToLower(oneResult.Destination), "united kingdom") {
-						displayRegion = "United Kingdom"
-					}
-				}
-
-				if displayRegion == "" {
-					displayRegion = "UNKNOWN"
-				}
-
-				// Add the country calls to the list and/or sum the call number
-				var found bool
-				for i, countryCall := range countryCalls {
-					if countryCall.Country == displayRegion && countryCall.Day == oneResult.Day {
-						countryCalls[i].Calls += oneResult.Calls
-						found = true
-						break
-					}
-				}
-				if !found {
-					countryCalls = append(countryCalls, ModelMorMaxCallsNumberPerDaysByCountry{Country: displayRegion, Calls: oneResult.Calls, Day: oneResult.Day})
-				}
-			} else {
-				// Handle the case where phone number information cannot be parsed.
-				displayRegion := "UNKNOWN"
-
-				// Add the country calls to the list and/or sum the call number
-				var found bool
-				for i, countryCall := range countryCalls {
-					if countryCall.Country == displayRegion && countryCall.Day == oneResult.Day {
-						countryCalls[i].Calls += oneResult.Calls
-						found = true
-						break
-					}
-				}
-				if !found {
-					countryCalls = append(countryCalls, ModelMorMaxCallsNumberPerDaysByCountry{Country: displayRegion, Calls: oneResult.Calls, Day: oneResult.Day})
+			// Retrieve the country of the destination.
+			_, displayRegion := getCountryFromPrefix(oneResult.Prefix, oneResult.Destination)
+
+			// Add the country calls to the list and/or sum the call number
+			var found bool
+			for i, countryCall := range countryCalls {
+				if countryCall.Country == displayRegion && countryCall.Day == oneResult.Day {
+					countryCalls[i].Calls += oneResult.Calls
+					found = true
+					break
 				}
 			}
-
+			if !found {
+				countryCalls = append(countryCalls, ModelMorMaxCallsNumberPerDaysByCountry{Country: displayRegion, Calls: oneResult.Calls, Day: oneResult.Day})
+			}
 		}
 
 		// Write into the file
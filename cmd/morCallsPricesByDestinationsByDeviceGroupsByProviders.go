@@ -9,14 +9,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/nyaruka/phonenumbers"
-	"github.com/pariz/gountries"
 	"github.com/spf13/cobra"
 )
 
 // Initialize the command.
 func init() {
 	rootCmd.AddCommand(morCallsPricesByDestinationsByDeviceGroupsByProvidersCmd)
+	registerReport(morCallsPricesByDestinationsByDeviceGroupsByProvidersCmd, "Device group;Country;Destination;Prefix;Price", "Device group", "Country", "Destination", "Prefix")
 	morCallsPricesByDestinationsByDeviceGroupsByProvidersCmd.Flags().StringP("dateStart", "s", "", "The start date of the export")
 	morCallsPricesByDestinationsByDeviceGroupsByProvidersCmd.Flags().StringP("dateEnd", "e", "", "The end date of the export")
 }
@@ -81,8 +80,7 @@ Example:
 This command export the prices of the answered outgoing calls from MOR database, grouped by device groups, filtered by providers and devices, and organized by destination. It generates a CSV file with the specified start and end date. The CSV file contains information about device group, country, destination, prefix, price, duration, duration (in hours), calls numbers, average Price per Minute, average prince per Calls, average duration per calls. The generated CSV file is named with a timestamp and saved in the current working directory.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Obtain the start and end date strings from the command-line flags.
-		dateStartStr, _ := cmd.Flags().GetString("dateStart")
-		dateEndStr, _ := cmd.Flags().GetString("dateEnd")
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
 
 		// Parse the provided start and end dates.
 		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
@@ -100,31 +98,13 @@ This command export the prices of the answered outgoing calls from MOR database,
 		// Display the start and end date information for the user's reference.
 		fmt.Println("morCallsPricesByDestinationsByDeviceGroupsByProviders called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
 
-		// Define mapping of device groups to source devices.
-		srcGroupDevicesID := map[string][]string{
-			"EN": {"181", "1081"},
-			"FR": {"671", "1072"},
-		}
-
-		// List of provider IDs.
-		providersID := []string{"561", "721", "21", "31", "101", "111", "441", "711", "781", "801"}
-
-		// Initialize variables to store SQL filters and device IDs.
-		srcDevicesIDFilter := ""
-		srcDevicesIDList := ""
-
-		// Build SQL filters based on the mapping of device groups to source devices.
-		for oneSrcGroup, oneSrcGroupDevicesID := range srcGroupDevicesID {
-			srcDevicesIDFilter += fmt.Sprintf("			WHEN src_device_id IN (%s) THEN '%s' \n", strings.Join(oneSrcGroupDevicesID, ","), oneSrcGroup)
-			srcDevicesIDList += strings.Join(oneSrcGroupDevicesID, ",")
-		}
+		// Build the device group SQL filter and the list of grouped devices.
+		srcDevicesIDFilter, srcDevicesIDList := getDeviceGroupFilter("src_device_id")
 
 		// Construct the SQL query with placeholders.
 		request := fmt.Sprintf(`
 		SELECT
-		CASE
-%s
-        	END AS DeviceGroup,
+		%s AS DeviceGroup,
         	mor.destinations.name AS Destination,
         	c.prefix as Prefix,
         	REPLACE(CAST(ROUND(SUM(provider_price), 2) AS CHAR), '.', ',') AS Price,
@@ -156,8 +136,7 @@ This command export the prices of the answered outgoing calls from MOR database,
 		}
 
 		// Generate a filename for the output file.
-		now := time.Now()
-		filename := fmt.Sprintf("%d_%02d_%02d_%02d_%02d_%02d_export.csv", now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second())
+		filename := getExportFilename(cmd)
 
 		// Create and open the output file for writing.
 		outputFile, err := os.Create(filename)
@@ -226,66 +205,11 @@ This command export the prices of the answered outgoing calls from MOR database,
 				averageDurationCalls = averageDurationCalls[:4]
 			}
 
-			// Process and format the prefix for phone numbers.
-			oneResult.Prefix = "+" + oneResult.Prefix
-			oneResult.Prefix = strings.TrimRight(oneResult.Prefix+"000000", " ")[0:6]
-
-			// Parse and format phone number information.
-			phoneNumber, err := phonenumbers.Parse(oneResult.Prefix, "")
-			if err == nil {
-				// Format the phone number in international format using the phonenumbers library.
-				formattedPrefix := phonenumbers.Format(phoneNumber, phonenumbers.INTERNATIONAL)
-
-				// Remove the + to the formatted phone prefix.
-				formattedPrefix = strings.Replace(formattedPrefix, "+", "", 1)
-
-				// Retrieve the region (country) code associated with the phone number.
-				regionCode := phonenumbers.GetRegionCodeForNumber(phoneNumber)
-
-				// Initialize a new query object for the gountries library, which will be used to fetch additional country information.
-				query := gountries.New()
-
-				// Find the country information based on the region code.
-				displayRegionQuery, _ := query.FindCountryByAlpha(regionCode)
-				displayRegion := displayRegionQuery.Name.Common
-
-				if displayRegion == "" {
-					// If the region is not found, provide fallback information for certain countries.
-					if strings.Contains(strings.ToLower(oneResult.Destination), "australia") || strings.Contains(strings.ToLower(oneResult.Destination), "australie") {
-						displayRegion = "Australia"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "canada") {
-						displayRegion = "Canada"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "italy") {
-						displayRegion = "Italy"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "russia") {
-						displayRegion = "Russia"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "unites states") {
-						displayRegion = "United States"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "guadeloupe") {
-						displayRegion = "France"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "morocco") {
-						displayRegion = "Morocco"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "reunion") || strings.Contains(strings.ToLower(oneResult.Destination), "r?union") || strings.Contains(strings.ToLower(oneResult.Destination), "france") {
-						displayRegion = "France"
-					} else if strings.Contains(strings.ToLower(oneResult.Destination), "uk") || strings.Contains(strings.ToLower(oneResult.Destination), "united kingdom") {
-						displayRegion = "United Kingdom"
-					}
-				}
-
-				if displayRegion == "" {
-					displayRegion = "UNKNOWN"
-				}
-
-				// Write the formatted result to the output file.
-				fmt.Fprintf(outputFile, "%s;%s;%s;%s;%s;%d;%s;%d;%s;%s;%s\n", oneResult.DeviceGroup, displayRegion, oneResult.Destination, formattedPrefix, oneResult.Price, oneResult.Duration, durationHourMinSeconds, oneResult.Calls, averagePriceMin, averagePriceCalls, averageDurationCalls)
-			} else {
-				// Handle the case where phone number information cannot be parsed.
-				displayRegion := "UNKNOWN"
-				formattedPrefix := "UNKNOWN"
-
-				// Write the result with unknown information to the output file.
-				fmt.Fprintf(outputFile, "%s;%s;%s;%s;%s;%d;%s;%d;%s;%s;%s\n", oneResult.DeviceGroup, displayRegion, oneResult.Destination, formattedPrefix, oneResult.Price, oneResult.Duration, durationHourMinSeconds, oneResult.Calls, averagePriceMin, averagePriceCalls, averageDurationCalls)
-			}
+			// Retrieve the formatted prefix and the country of the destination.
+			formattedPrefix, displayRegion := getCountryFromPrefix(oneResult.Prefix, oneResult.Destination)
+
+			// Write the formatted result to the output file.
+			fmt.Fprintf(outputFile, "%s;%s;%s;%s;%s;%d;%s;%d;%s;%s;%s\n", oneResult.DeviceGroup, displayRegion, oneResult.Destination, formattedPrefix, oneResult.Price, oneResult.Duration, durationHourMinSeconds, oneResult.Calls, averagePriceMin, averagePriceCalls, averageDurationCalls)
 		}
 		// Log a message indicating the filename of the exported data.
 		log.Printf("%s exported", filename)
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsQualityPerDaysByProvidersByDestinations)
+	registerReport(morCallsQualityPerDaysByProvidersByDestinations, "Day;Provider;Country;Attempts", "Day", "Provider", "Country")
+	registerIncremental(morCallsQualityPerDaysByProvidersByDestinations, true)
+	morCallsQualityPerDaysByProvidersByDestinations.Flags().StringP("dateStart", "s", "", "The start date of the export")
+	morCallsQualityPerDaysByProvidersByDestinations.Flags().StringP("dateEnd", "e", "", "The end date of the export")
+}
+
+// Q.850 names of the most common hangup causes.
+var hangupCausesName = map[int]string{
+	1:   "Unallocated number",
+	3:   "No route to destination",
+	16:  "Normal clearing",
+	17:  "User busy",
+	18:  "No user responding",
+	19:  "No answer",
+	21:  "Call rejected",
+	22:  "Number changed",
+	27:  "Destination out of order",
+	28:  "Invalid number format",
+	31:  "Normal unspecified",
+	34:  "No circuit available",
+	38:  "Network out of order",
+	41:  "Temporary failure",
+	42:  "Switching equipment congestion",
+	44:  "Requested channel not available",
+	50:  "Requested facility not subscribed",
+	58:  "Bearer capability not available",
+	88:  "Incompatible destination",
+	102: "Recovery on timer expiry",
+	127: "Interworking unspecified",
+}
+
+// Hangup causes which mean that the network has done its job even if the call has not been answered.
+var networkEffectiveHangupCauses = map[int]bool{
+	17: true,
+	18: true,
+	19: true,
+	21: true,
+}
+
+// ModelMorCallsQualityPerDaysByProvidersByDestinations represents the calls of a provider and a destination grouped by disposition and hangup cause.
+type ModelMorCallsQualityPerDaysByProvidersByDestinations struct {
+	Day         string
+	Provider    string
+	Destination string
+	Prefix      string
+	Disposition string
+	HangupCause int
+	Calls       int
+	Billsec     int
+	Pdd         float64
+	PddCalls    int
+}
+
+// ModelMorCallsQualityPerDaysByProvidersByCountry represents the quality of a provider for a country and a day.
+type ModelMorCallsQualityPerDaysByProvidersByCountry struct {
+	Day          string
+	Provider     string
+	Country      string
+	Attempts     int
+	Answered     int
+	Busy         int
+	NoAnswer     int
+	Failed       int
+	NetworkCalls int
+	Billsec      int
+	Pdd          float64
+	PddCalls     int
+	HangupCauses map[int]int
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelMorCallsQualityPerDaysByProvidersByDestinations(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorCallsQualityPerDaysByProvidersByDestinations
+
+		if err := rows.Scan(
+			&msg.Day,
+			&msg.Provider,
+			&msg.Destination,
+			&msg.Prefix,
+			&msg.Disposition,
+			&msg.HangupCause,
+			&msg.Calls,
+			&msg.Billsec,
+			&msg.Pdd,
+			&msg.PddCalls,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Format a ratio as a percentage with two decimals.
+func formatPercent(value int, total int) string {
+	if total == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(value)*100/float64(total), 'f', 2, 64)
+}
+
+// Format the hangup causes as "cause name: calls" sorted by the number of calls.
+func formatHangupCauses(hangupCauses map[int]int) string {
+	var causes []int
+	for cause := range hangupCauses {
+		causes = append(causes, cause)
+	}
+	sort.Slice(causes, func(i, j int) bool {
+		if hangupCauses[causes[i]] == hangupCauses[causes[j]] {
+			return causes[i] < causes[j]
+		}
+		return hangupCauses[causes[i]] > hangupCauses[causes[j]]
+	})
+
+	var formattedCauses []string
+	for _, cause := range causes {
+		name, ok := hangupCausesName[cause]
+		if !ok {
+			name = "Cause"
+		}
+		formattedCauses = append(formattedCauses, fmt.Sprintf("%s (%d): %d", name, cause, hangupCauses[cause]))
+	}
+
+	return strings.Join(formattedCauses, ", ")
+}
+
+// Define the main Cobra command for exporting the providers quality.
+var morCallsQualityPerDaysByProvidersByDestinations = &cobra.Command{
+	Use:   "morCallsQualityPerDaysByProvidersByDestinations",
+	Short: "Export the quality (ASR, ACD, NER, PDD, hangup causes) of the outgoing calls per days by providers and by destinations.",
+	Long: `Export the quality of the outgoing calls from MOR database per days, grouped by providers and destination countries, computed from the calls dispositions. The CSV will include the following columns: Day, Provider, Country, Attempts, Answered, Busy, No answer, Failed, ASR (%), ACD (seconds), NER (%), Average PDD (seconds), Duration (hours), Hangup causes.
+
+Usage:
+  morCallsQualityPerDaysByProvidersByDestinations -s [start_date] -e [end_date]
+
+Flags:
+  -s, --dateStart string   The start date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string     The end date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+
+Example:
+  morCallsQualityPerDaysByProvidersByDestinations -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59"
+
+This command export the quality of the outgoing calls sent to the providers of the providersID list. ASR is the answered calls divided by the attempts, ACD is the average billed seconds of the answered calls, NER is the answered, busy, not responding, not answered and rejected calls divided by the attempts. The generated CSV file is named with a timestamp and saved in the current working directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morCallsQualityPerDaysByProvidersByDestinations called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			DATE(c.calldate) AS Day,
+			p.name AS Provider,
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix,
+			c.disposition AS Disposition,
+			IFNULL(c.hangupcause, 0) AS HangupCause,
+			count(*) AS Calls,
+			SUM(CASE WHEN c.disposition = 'ANSWERED' THEN c.billsec ELSE 0 END) AS Billsec,
+			IFNULL(SUM(CASE WHEN c.pdd > 0 THEN c.pdd ELSE 0 END), 0) AS Pdd,
+			SUM(CASE WHEN c.pdd > 0 THEN 1 ELSE 0 END) AS PddCalls
+		FROM mor.calls c
+		INNER JOIN mor.providers p ON c.provider_id = p.id
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.provider_id IN (%s)
+		GROUP BY Day, Provider, Destination, Prefix, Disposition, HangupCause
+		ORDER BY Day, Provider, Destination;`, dateStartStr, dateEndStr, strings.Join(providersID, ","))
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorCallsQualityPerDaysByProvidersByDestinations)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Create a list to hold the results casted to the desired data model.
+		var resultsCasted []ModelMorCallsQualityPerDaysByProvidersByDestinations
+		for _, result := range results {
+			resultsCasted = append(resultsCasted, result.(ModelMorCallsQualityPerDaysByProvidersByDestinations))
+		}
+
+		// Aggregate the results per day, provider and country.
+		var countriesQuality []*ModelMorCallsQualityPerDaysByProvidersByCountry
+		countriesQualityIndex := make(map[string]*ModelMorCallsQualityPerDaysByProvidersByCountry)
+		for _, oneResult := range resultsCasted {
+			// Retrieve the country of the destination.
+			_, displayRegion := getCountryFromPrefix(oneResult.Prefix, oneResult.Destination)
+
+			// Find or create the quality of the day, provider and country.
+			key := oneResult.Day + ";" + oneResult.Provider + ";" + displayRegion
+			countryQuality, found := countriesQualityIndex[key]
+			if !found {
+				countryQuality = &ModelMorCallsQualityPerDaysByProvidersByCountry{Day: oneResult.Day, Provider: oneResult.Provider, Country: displayRegion, HangupCauses: make(map[int]int)}
+				countriesQualityIndex[key] = countryQuality
+				countriesQuality = append(countriesQuality, countryQuality)
+			}
+
+			// Sum the calls by disposition.
+			countryQuality.Attempts += oneResult.Calls
+			switch oneResult.Disposition {
+			case "ANSWERED":
+				countryQuality.Answered += oneResult.Calls
+			case "BUSY":
+				countryQuality.Busy += oneResult.Calls
+			case "NO ANSWER":
+				countryQuality.NoAnswer += oneResult.Calls
+			default:
+				countryQuality.Failed += oneResult.Calls
+			}
+
+			// Count the calls where the network has reached the called party.
+			if oneResult.Disposition == "ANSWERED" || networkEffectiveHangupCauses[oneResult.HangupCause] {
+				countryQuality.NetworkCalls += oneResult.Calls
+			}
+
+			// Sum the durations and the hangup causes.
+			countryQuality.Billsec += oneResult.Billsec
+			countryQuality.Pdd += oneResult.Pdd
+			countryQuality.PddCalls += oneResult.PddCalls
+			if oneResult.Disposition != "ANSWERED" {
+				countryQuality.HangupCauses[oneResult.HangupCause] += oneResult.Calls
+			}
+		}
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Day;Provider;Country;Attempts;Answered;Busy;No answer;Failed;ASR (%);ACD (seconds);NER (%);Average PDD (seconds);Duration (hours);Hangup causes")
+
+		// Process and write each result to the output file.
+		for _, countryQuality := range countriesQuality {
+			// Calculate the average call duration of the answered calls.
+			acd := 0
+			if countryQuality.Answered > 0 {
+				acd = countryQuality.Billsec / countryQuality.Answered
+			}
+
+			// Calculate the average post dial delay.
+			averagePdd := float64(0)
+			if countryQuality.PddCalls > 0 {
+				averagePdd = countryQuality.Pdd / float64(countryQuality.PddCalls)
+			}
+
+			// Write the formatted result to the output file.
+			fmt.Fprintf(outputFile, "%s;%s;%s;%d;%d;%d;%d;%d;%s;%d;%s;%s;%s;%s\n", countryQuality.Day, countryQuality.Provider, countryQuality.Country, countryQuality.Attempts, countryQuality.Answered, countryQuality.Busy, countryQuality.NoAnswer, countryQuality.Failed, formatPercent(countryQuality.Answered, countryQuality.Attempts), acd, formatPercent(countryQuality.NetworkCalls, countryQuality.Attempts), strconv.FormatFloat(averagePdd, 'f', 2, 64), formatTimeSecondsToHours(countryQuality.Billsec), formatHangupCauses(countryQuality.HangupCauses))
+		}
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
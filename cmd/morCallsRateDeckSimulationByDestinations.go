@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morCallsRateDeckSimulationByDestinations)
+	registerReport(morCallsRateDeckSimulationByDestinations, "Country;Destination;Prefix;Calls", "Prefix")
+	morCallsRateDeckSimulationByDestinations.Flags().StringP("file", "f", "", "The CSV file of the rate deck of the candidate provider")
+	morCallsRateDeckSimulationByDestinations.Flags().StringP("name", "n", "Candidate", "The name of the candidate provider")
+	morCallsRateDeckSimulationByDestinations.Flags().StringP("dateStart", "s", "", "The start date of the simulated traffic (default: first day of last month)")
+	morCallsRateDeckSimulationByDestinations.Flags().StringP("dateEnd", "e", "", "The end date of the simulated traffic (default: last day of last month)")
+}
+
+// rateDeckSimulationRow represents the calls of a destination with their cost on the current providers and on the candidate provider.
+type rateDeckSimulationRow struct {
+	country        string
+	destination    string
+	prefix         string
+	calls          int
+	billsec        int
+	currentCost    float64
+	providersCost  map[string]float64
+	providersCalls map[string]int
+	candidateCost  float64
+	candidateCalls int
+}
+
+// Define the main Cobra command for simulating the traffic on a candidate rate deck.
+var morCallsRateDeckSimulationByDestinations = &cobra.Command{
+	Use:   "morCallsRateDeckSimulationByDestinations",
+	Short: "Simulate the cost of the calls by destinations with the rate deck of a candidate provider compared with the current providers.",
+	Long: `Load the rate deck of a candidate provider from a CSV file, re-rate the answered calls sent to the providers of the providersID list with it and with the MOR tariffs of the current providers, and export by destination the current cost, the cheapest current provider, the candidate cost and the delta. The CSV will include the following columns: Country, Destination, Prefix, Calls, Minutes, Current cost, Cheapest current provider, Cheapest current cost, Candidate cost, Candidate rated calls, Delta, Delta (%), Switch.
+
+Usage:
+  morCallsRateDeckSimulationByDestinations -f [file] [-n [name]] [-s [start_date] -e [end_date]]
+
+Flags:
+  -f, --file string        The CSV file of the rate deck of the candidate provider
+  -n, --name string        The name of the candidate provider (default "Candidate")
+  -s, --dateStart string   The start date of the simulated traffic (e.g., 'YYYY-MM-DD HH:mm:SS', default: first day of last month)
+  -e, --dateEnd string     The end date of the simulated traffic (e.g., 'YYYY-MM-DD HH:mm:SS', default: last day of last month)
+
+Example:
+  morCallsRateDeckSimulationByDestinations -f new_carrier.csv -n "New carrier"
+
+The rate deck file is separated by semicolons with a header row naming the columns Prefix and Rate (per minute), and optionally Connection fee, Increment and Min time. The current providers are rated from the MOR destination prefix of the calls and the candidate provider from the dialed number, so its rate deck can have longer prefixes than the MOR destinations. The current cost is the provider price of the calls, the delta is the candidate cost minus the current cost, and the Switch column is YES when the candidate rates all the calls of the destination for less. The rows are sorted by delta and a Total row ends the file. The generated CSV file is named with a timestamp and saved in the current working directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the file, the name and the start and end date strings from the command-line flags.
+		file, _ := cmd.Flags().GetString("file")
+		name, _ := cmd.Flags().GetString("name")
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+
+		// Simulate the traffic of last month when no dates are given.
+		now := time.Now()
+		firstDayOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+		if dateStartStr == "" {
+			dateStartStr = firstDayOfMonth.AddDate(0, -1, 0).Format("2006-01-02 15:04:05")
+		}
+		if dateEndStr == "" {
+			dateEndStr = firstDayOfMonth.Add(-time.Second).Format("2006-01-02 15:04:05")
+		}
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Load the rate deck of the candidate provider.
+		candidateDeck, err := readRateDeckFile(file, name)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		// Display the file and the date information for the user's reference.
+		fmt.Println("morCallsRateDeckSimulationByDestinations called with file: " + file + ", dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Load the current providers with their tariff and the rate decks of the tariffs.
+		providers, err := getMorProvidersTariffs()
+		if err != nil {
+			log.Fatal(err)
+		}
+		rateDecks, err := getMorRateDecks()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			c.calldate AS CallDate,
+			c.provider_id AS ProviderID,
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix,
+			c.billsec AS Billsec,
+			IFNULL(c.provider_price, 0) AS Price,
+			IFNULL(c.dst, '') AS Dst
+		FROM mor.calls c
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.provider_id IN (%s) AND
+			c.disposition = 'ANSWERED';`, dateStartStr, dateEndStr, strings.Join(providersID, ","))
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and re-rate each call while it is read.
+		var prefixes []string
+		simulationRows := make(map[string]*rateDeckSimulationRow)
+		_, err = MorRequest(request, func(stmt *sql.Stmt) ([]any, error) {
+			rows, err := stmt.Query()
+			if err != nil {
+				return nil, err
+			}
+
+			defer rows.Close()
+
+			for rows.Next() {
+				call, err := getModelMorCallsLeastCostRoutingCall(rows)
+				if err != nil {
+					return nil, err
+				}
+				callDate, err := time.Parse("2006-01-02 15:04:05", call.CallDate)
+				if err != nil {
+					return nil, err
+				}
+
+				// Retrieve the row of the destination.
+				row, found := simulationRows[call.Prefix]
+				if !found {
+					_, country := getCountryFromPrefix(call.Prefix, call.Destination)
+					row = &rateDeckSimulationRow{country: country, destination: call.Destination, prefix: call.Prefix, providersCost: make(map[string]float64), providersCalls: make(map[string]int)}
+					simulationRows[call.Prefix] = row
+					prefixes = append(prefixes, call.Prefix)
+				}
+				row.calls++
+				row.billsec += call.Billsec
+				row.currentCost += call.Price
+
+				// Compute the cost of the call on each current provider with a rate for its prefix.
+				for _, provider := range providers {
+					deck, found := rateDecks[provider.TariffID]
+					if !found {
+						continue
+					}
+					if rate, found := deck.findRate(call.Prefix, callDate); found {
+						row.providersCost[provider.Name] += computeCallPrice(rate, call.Billsec)
+						row.providersCalls[provider.Name]++
+					}
+				}
+
+				// Compute the cost of the call on the candidate provider from the dialed number, its rate deck can have longer
+				// prefixes than the MOR destinations.
+				number := removeZero(call.Dst)
+				if number == "" {
+					number = call.Prefix
+				}
+				if rate, found := candidateDeck.findRate(number, callDate); found {
+					row.candidateCost += computeCallPrice(rate, call.Billsec)
+					row.candidateCalls++
+				}
+			}
+
+			return nil, rows.Err()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Compute the delta of the destinations fully rated by the candidate provider.
+		deltas := make(map[string]float64)
+		for _, prefix := range prefixes {
+			row := simulationRows[prefix]
+			if row.candidateCalls == row.calls {
+				deltas[prefix] = row.candidateCost - row.currentCost
+			}
+		}
+		sort.SliceStable(prefixes, func(i, j int) bool {
+			deltaI, ratedI := deltas[prefixes[i]]
+			deltaJ, ratedJ := deltas[prefixes[j]]
+			if ratedI != ratedJ {
+				return ratedI
+			}
+			return deltaI < deltaJ
+		})
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Country;Destination;Prefix;Calls;Minutes;Current cost;Cheapest current provider;Cheapest current cost;Candidate cost;Candidate rated calls;Delta;Delta (%);Switch")
+
+		// Process and write each destination to the output file.
+		totalCalls := 0
+		totalBillsec := 0
+		totalCurrentCost := float64(0)
+		totalCandidateCost := float64(0)
+		totalDelta := float64(0)
+		switchSavings := float64(0)
+		switchDestinations := 0
+		for _, prefix := range prefixes {
+			row := simulationRows[prefix]
+			totalCalls += row.calls
+			totalBillsec += row.billsec
+			totalCurrentCost += row.currentCost
+			totalCandidateCost += row.candidateCost
+
+			// Find the cheapest current provider able to carry all the calls.
+			cheapest := ""
+			for _, provider := range providers {
+				if row.providersCalls[provider.Name] == row.calls && (cheapest == "" || row.providersCost[provider.Name] < row.providersCost[cheapest]) {
+					cheapest = provider.Name
+				}
+			}
+			cheapestCost := ""
+			if cheapest != "" {
+				cheapestCost = formatPrice(row.providersCost[cheapest])
+			}
+
+			// The delta is empty when the candidate provider does not rate all the calls.
+			delta := ""
+			deltaPercent := ""
+			switchProvider := "NO"
+			if value, found := deltas[prefix]; found {
+				totalDelta += value
+				delta = formatPrice(value)
+				if row.currentCost != 0 {
+					deltaPercent = strconv.FormatFloat(value*100/row.currentCost, 'f', 2, 64)
+				}
+				if value < 0 {
+					switchProvider = "YES"
+					switchSavings -= value
+					switchDestinations++
+				}
+			}
+
+			// Write the formatted result to the output file.
+			fmt.Fprintf(outputFile, "%s;%s;%s;%d;%s;%s;%s;%s;%s;%d;%s;%s;%s\n", row.country, row.destination, row.prefix, row.calls, strconv.FormatFloat(float64(row.billsec)/60, 'f', 2, 64), formatPrice(row.currentCost), cheapest, cheapestCost, formatPrice(row.candidateCost), row.candidateCalls, delta, deltaPercent, switchProvider)
+		}
+
+		// Write the total row to the output file.
+		fmt.Fprintf(outputFile, "Total;;;%d;%s;%s;;;%s;;%s;;%d\n", totalCalls, strconv.FormatFloat(float64(totalBillsec)/60, 'f', 2, 64), formatPrice(totalCurrentCost), formatPrice(totalCandidateCost), formatPrice(totalDelta), switchDestinations)
+
+		// Display the summary of the simulation.
+		fmt.Printf("Current cost %s, delta with %s on the rated destinations %s, savings of the %d destinations where switching pays off %s\n", formatPrice(totalCurrentCost), name, formatPrice(totalDelta), switchDestinations, formatPrice(switchSavings))
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
@@ -13,6 +13,7 @@ import (
 // Initialize the command.
 func init() {
 	rootCmd.AddCommand(morIncomingCallsDuration)
+	registerReport(morIncomingCallsDuration, "Did;Seconds;Calls", "Did")
 	morIncomingCallsDuration.Flags().StringP("dateStart", "s", "", "The start date of the export")
 	morIncomingCallsDuration.Flags().StringP("dateEnd", "e", "", "The end date of the export")
 }
@@ -83,8 +84,7 @@ Example:
 This command export incoming call duration data for a specified date range. It generates a CSV file with the specified start and end date. The CSV will include the following columns: Did, Seconds, Calls, Provider, Username, Extension, Description, Status, UpdateDate, Duration (hours). The generated CSV file is named with a timestamp and saved in the current working directory.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Obtain the start and end date strings from the command-line flags.
-		dateStartStr, _ := cmd.Flags().GetString("dateStart")
-		dateEndStr, _ := cmd.Flags().GetString("dateEnd")
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
 
 		// Parse the provided start and end dates.
 		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
@@ -136,8 +136,7 @@ This command export incoming call duration data for a specified date range. It g
 		}
 
 		// Generate a filename for the output file.
-		now := time.Now()
-		filename := fmt.Sprintf("%d_%02d_%02d_%02d_%02d_%02d_export.csv", now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second())
+		filename := getExportFilename(cmd)
 
 		// Create and open the output file for writing.
 		outputFile, err := os.Create(filename)
@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nyaruka/phonenumbers"
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morIncomingCallsOriginByDidsByProviders)
+	registerReport(morIncomingCallsOriginByDidsByProviders, "Dimension;Value;Provider;Calls", "Dimension", "Value")
+	morIncomingCallsOriginByDidsByProviders.Flags().StringP("dateStart", "s", "", "The start date of the export")
+	morIncomingCallsOriginByDidsByProviders.Flags().StringP("dateEnd", "e", "", "The end date of the export")
+}
+
+// Caller IDs of the anonymous or withheld calls, in lower case.
+var anonymousCallerIDs = []string{"anonymous", "unknown", "restricted", "private", "withheld", "unavailable"}
+
+// ModelMorIncomingCallsOrigin represents the calls of a caller to a DID.
+type ModelMorIncomingCallsOrigin struct {
+	Did      string
+	Provider string
+	Src      string
+	Calls    int
+	Answered int
+}
+
+// incomingCallsOrigin represents the callers of a DID or of a provider.
+type incomingCallsOrigin struct {
+	calls          int
+	answered       int
+	callers        map[string]int
+	anonymousCalls int
+	mobileCalls    int
+	fixedCalls     int
+	otherCalls     int
+	countries      map[string]int
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelMorIncomingCallsOrigin(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorIncomingCallsOrigin
+
+		if err := rows.Scan(
+			&msg.Did,
+			&msg.Provider,
+			&msg.Src,
+			&msg.Calls,
+			&msg.Answered,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Check if a caller ID is anonymous or withheld: empty, without digits or a known anonymous caller ID.
+func isAnonymousCallerID(src string) bool {
+	src = strings.ToLower(strings.TrimSpace(src))
+	for _, anonymousCallerID := range anonymousCallerIDs {
+		if strings.Contains(src, anonymousCallerID) {
+			return true
+		}
+	}
+	return strings.Trim(src, "+0") == "" || strings.IndexAny(src, "123456789") < 0
+}
+
+// Format the countries as "country: calls" sorted by the number of calls.
+func formatCountries(countries map[string]int) string {
+	var names []string
+	for name := range countries {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if countries[names[i]] == countries[names[j]] {
+			return names[i] < names[j]
+		}
+		return countries[names[i]] > countries[names[j]]
+	})
+
+	var formattedCountries []string
+	for _, name := range names {
+		formattedCountries = append(formattedCountries, fmt.Sprintf("%s: %d", name, countries[name]))
+	}
+
+	return strings.Join(formattedCountries, ", ")
+}
+
+// Define the main Cobra command for exporting the origin of the incoming calls.
+var morIncomingCallsOriginByDidsByProviders = &cobra.Command{
+	Use:   "morIncomingCallsOriginByDidsByProviders",
+	Short: "Export the origin of the callers of the incoming calls by DIDs and by providers.",
+	Long: `Export the callers of the incoming calls from the MOR database by DIDs and by DID providers: origin countries, number types, anonymous calls, unique and repeat callers. The CSV will include the following columns: Dimension, Value, Provider, Calls, Answered, Unique callers, Repeat callers (%), Anonymous calls, Anonymous calls (%), Mobile calls, Fixed calls, Other calls, Countries.
+
+Usage:
+  morIncomingCallsOriginByDidsByProviders -s [start_date] -e [end_date]
+
+Flags:
+  -s, --dateStart string   The start date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string     The end date of the export (e.g., 'YYYY-MM-DD HH:mm:SS')
+
+Example:
+  morIncomingCallsOriginByDidsByProviders -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59"
+
+This command normalize the caller numbers like the outgoing reports. The Dimension column is DID or Provider, the repeat callers are the callers with more than one call, the anonymous calls are not counted in the callers, the number types and the countries. The Other calls column counts the numbers that cannot be parsed or that are not only mobile or fixed. The generated CSV file is named with a timestamp and saved in the current working directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the start and end date strings from the command-line flags.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Display the start and end date information for the user's reference.
+		fmt.Println("morIncomingCallsOriginByDidsByProviders called with dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			d.did AS Did,
+			IFNULL(p.name, '') AS Provider,
+			IFNULL(c.src, '') AS Src,
+			count(*) AS Calls,
+			SUM(CASE WHEN c.disposition = 'ANSWERED' THEN 1 ELSE 0 END) AS Answered
+		FROM mor.calls c
+		INNER JOIN mor.dids d ON c.did_id = d.id
+		LEFT JOIN mor.providers p ON d.provider_id = p.id
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.did_id > 0
+		GROUP BY Did, Provider, Src
+		ORDER BY Did;`, dateStartStr, dateEndStr)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorIncomingCallsOrigin)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Add the calls of each caller to its DID and to its provider.
+		var dimensions []string
+		dimensionsProvider := make(map[string]string)
+		origins := make(map[string]*incomingCallsOrigin)
+		for _, result := range results {
+			oneResult := result.(ModelMorIncomingCallsOrigin)
+
+			// Normalize the caller number.
+			anonymous := isAnonymousCallerID(oneResult.Src)
+			caller := ""
+			country := ""
+			numberType := phonenumbers.UNKNOWN
+			if !anonymous {
+				e164, regionCode, oneNumberType, err := getNumberInformation(oneResult.Src)
+				if err == nil {
+					caller = e164
+					country = getCountryName(regionCode)
+					numberType = oneNumberType
+				} else {
+					caller = oneResult.Src
+					country = "UNKNOWN"
+				}
+			}
+
+			for _, key := range []string{"DID;" + oneResult.Did, "Provider;" + oneResult.Provider} {
+				origin, found := origins[key]
+				if !found {
+					origin = &incomingCallsOrigin{callers: make(map[string]int), countries: make(map[string]int)}
+					origins[key] = origin
+					dimensions = append(dimensions, key)
+					if strings.HasPrefix(key, "DID;") {
+						dimensionsProvider[key] = oneResult.Provider
+					}
+				}
+				origin.calls += oneResult.Calls
+				origin.answered += oneResult.Answered
+				if anonymous {
+					origin.anonymousCalls += oneResult.Calls
+					continue
+				}
+				origin.callers[caller] += oneResult.Calls
+				origin.countries[country] += oneResult.Calls
+				switch numberType {
+				case phonenumbers.MOBILE:
+					origin.mobileCalls += oneResult.Calls
+				case phonenumbers.FIXED_LINE:
+					origin.fixedCalls += oneResult.Calls
+				default:
+					origin.otherCalls += oneResult.Calls
+				}
+			}
+		}
+		sort.Strings(dimensions)
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Dimension;Value;Provider;Calls;Answered;Unique callers;Repeat callers (%);Anonymous calls;Anonymous calls (%);Mobile calls;Fixed calls;Other calls;Countries")
+
+		// Process and write the origin of the callers of each dimension to the output file.
+		for _, dimension := range dimensions {
+			origin := origins[dimension]
+
+			// Count the callers with more than one call.
+			repeatCallers := 0
+			for _, calls := range origin.callers {
+				if calls > 1 {
+					repeatCallers++
+				}
+			}
+
+			// Write the formatted result to the output file.
+			fmt.Fprintf(outputFile, "%s;%s;%d;%d;%d;%s;%d;%s;%d;%d;%d;%s\n", dimension, dimensionsProvider[dimension], origin.calls, origin.answered, len(origin.callers), formatPercent(repeatCallers, len(origin.callers)), origin.anonymousCalls, formatPercent(origin.anonymousCalls, origin.calls), origin.mobileCalls, origin.fixedCalls, origin.otherCalls, formatCountries(origin.countries))
+		}
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
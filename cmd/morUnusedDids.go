@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(morUnusedDids)
+	morUnusedDids.Flags().IntP("days", "d", 30, "The number of days without traffic")
+	morUnusedDids.Flags().IntP("maxCalls", "m", 0, "The maximum number of calls of an under-used DID during these days")
+	morUnusedDids.Flags().IntP("lastCallDays", "l", 365, "The number of days searched for the last call of the DIDs")
+}
+
+// Build the SQL joins of the traffic of the DIDs (alias d): the incoming calls received on the DID and the outgoing calls sent to
+// a provider with the DID as caller ID. The Calls columns count the calls since the period start. The LastCall columns are the last
+// incoming call since the date, and the last outgoing call since the period start (the outgoing calls are grouped by caller ID, so
+// they are only scanned during the period). Both the unused DIDs export and the idle DIDs check use this definition of a used DID.
+func getDidsTrafficJoin(dateStart string, periodStart string) string {
+	return fmt.Sprintf(`LEFT JOIN (
+			SELECT
+				c.did_id AS DidID,
+				SUM(CASE WHEN c.calldate > '%[2]s' THEN 1 ELSE 0 END) AS Calls,
+				MAX(c.calldate) AS LastCall
+			FROM mor.calls c
+			WHERE
+				c.calldate > '%[1]s' AND
+				c.did_id > 0
+			GROUP BY c.did_id
+		) incoming ON incoming.DidID = d.id
+		LEFT JOIN (
+			SELECT
+				c.src AS Src,
+				COUNT(*) AS Calls,
+				MAX(c.calldate) AS LastCall
+			FROM mor.calls c
+			WHERE
+				c.calldate > '%[2]s' AND
+				c.provider_id > 0 AND
+				c.src IN (SELECT did FROM mor.dids WHERE status = 'active')
+			GROUP BY c.src
+		) outgoing ON outgoing.Src = d.did`, dateStart, periodStart)
+}
+
+// ModelMorUnusedDids represents an active DID with its traffic during the last days.
+type ModelMorUnusedDids struct {
+	Did         string
+	ProviderID  string
+	Provider    string
+	Username    string
+	Extension   *string
+	Description *string
+	ClosedTill  *string
+	Calls       int
+	LastCall    *string
+}
+
+// Retrieve DID data from the database and return it as a slice of models.
+func getModelMorUnusedDids(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorUnusedDids
+
+		if err := rows.Scan(
+			&msg.Did,
+			&msg.ProviderID,
+			&msg.Provider,
+			&msg.Username,
+			&msg.Extension,
+			&msg.Description,
+			&msg.ClosedTill,
+			&msg.Calls,
+			&msg.LastCall,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Define the main Cobra command for exporting the unused DIDs.
+var morUnusedDids = &cobra.Command{
+	Use:   "morUnusedDids",
+	Short: "Export the active DIDs without (or with few) incoming and outgoing calls during the last days.",
+	Long: `Export the active DIDs from the MOR database without incoming and outgoing calls during the last days, or with a few calls, to release the unused numbers. The CSV will include the following columns: Did, Provider, Username, Extension, Description, UpdateDate, Calls, Last call, Idle days, Monthly cost.
+
+Usage:
+  morUnusedDids -d [days] -m [max_calls] [-l [last_call_days]]
+
+Flags:
+  -d, --days int           The number of days without traffic (default 30)
+  -m, --maxCalls int       The maximum number of calls of an under-used DID during these days (default 0)
+  -l, --lastCallDays int   The number of days searched for the last call of the DIDs (default 365)
+
+Example:
+  morUnusedDids -d 90 -m 5
+
+This command export the active DIDs with at most maxCalls incoming or outgoing calls during the last days, sorted by idle days. The incoming calls are the calls received on the DID and the outgoing calls are the calls sent to a provider with the DID as caller ID. The last call is the last incoming call of the DID during the lastCallDays days or its last outgoing call during the days, empty when there is none. MOR does not store the monthly rental cost of the DIDs (its DID rates are per minute): the monthly cost is read from the didsMonthlyCostByProvider variable of cmd/configHelper.go by provider of the DID, empty for the providers without a cost, and the last row is the total monthly cost of the exported DIDs. The generated CSV file is named with a timestamp and saved in the current working directory. This export does not accept the --compare option: it covers the last days instead of a date range.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the number of days and the maximum number of calls from the command-line flags.
+		days, _ := cmd.Flags().GetInt("days")
+		maxCalls, _ := cmd.Flags().GetInt("maxCalls")
+		lastCallDays, _ := cmd.Flags().GetInt("lastCallDays")
+
+		// Check the number of days and the maximum number of calls.
+		if days <= 0 || maxCalls < 0 || lastCallDays < days {
+			fmt.Println("Invalid days, maxCalls or lastCallDays. Please use a positive number of days, a maximum number of calls greater or equal to 0 and lastCallDays greater or equal to days")
+			return
+		}
+
+		// Compute the start date of the traffic period and of the last call search.
+		now := time.Now()
+		dateStartStr := now.AddDate(0, 0, -days).Format("2006-01-02 15:04:05")
+		lastCallStartStr := now.AddDate(0, 0, -lastCallDays).Format("2006-01-02 15:04:05")
+
+		// Display the period information for the user's reference.
+		fmt.Printf("morUnusedDids called with days: %d (since %s) and maxCalls: %d\n", days, dateStartStr, maxCalls)
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			d.did AS Did,
+			IFNULL(d.provider_id, '') AS ProviderID,
+			IFNULL(p.name, '') AS Provider,
+			IFNULL(u.username, '') AS Username,
+			dv.extension AS Extension,
+			dv.description AS Description,
+			d.closed_till AS ClosedTill,
+			IFNULL(incoming.Calls, 0) + IFNULL(outgoing.Calls, 0) AS Calls,
+			NULLIF(GREATEST(
+				IFNULL(incoming.LastCall, '0000-00-00 00:00:00'),
+				IFNULL(outgoing.LastCall, '0000-00-00 00:00:00')
+			), '0000-00-00 00:00:00') AS LastCall
+		FROM mor.dids d
+		%s
+		LEFT JOIN mor.providers p ON d.provider_id = p.id
+		LEFT JOIN mor.users u ON d.user_id = u.id
+		LEFT JOIN mor.devices dv ON d.device_id = dv.id
+		WHERE
+			d.status = 'active' AND
+			IFNULL(incoming.Calls, 0) + IFNULL(outgoing.Calls, 0) <= %d
+		ORDER BY LastCall, d.did;`, getDidsTrafficJoin(lastCallStartStr, dateStartStr), maxCalls)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelMorUnusedDids)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Create a list to hold the results casted to the desired data model.
+		var resultsCasted []ModelMorUnusedDids
+		for _, result := range results {
+			resultsCasted = append(resultsCasted, result.(ModelMorUnusedDids))
+		}
+
+		// Generate a filename for the output file.
+		filename := getExportFilename(cmd)
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Did;Provider;Username;Extension;Description;UpdateDate;Calls;Last call;Idle days;Monthly cost")
+
+		// Process and write each result to the output file.
+		totalMonthlyCost := float64(0)
+		for _, oneResult := range resultsCasted {
+			// Convert the nullable columns to strings.
+			extensionStr := ""
+			if oneResult.Extension != nil {
+				extensionStr = *oneResult.Extension
+			}
+			descriptionStr := ""
+			if oneResult.Description != nil {
+				descriptionStr = *oneResult.Description
+			}
+			closedTillStr := ""
+			if oneResult.ClosedTill != nil {
+				closedTillStr = *oneResult.ClosedTill
+			}
+
+			// Compute the idle days since the last call, empty if the DID has never been used.
+			lastCallStr := ""
+			idleDaysStr := ""
+			if oneResult.LastCall != nil {
+				lastCallStr = *oneResult.LastCall
+				lastCall, err := time.ParseInLocation("2006-01-02 15:04:05", lastCallStr, time.Local)
+				if err == nil {
+					idleDaysStr = fmt.Sprintf("%d", int(now.Sub(lastCall).Hours()/24))
+				}
+			}
+
+			// Retrieve the monthly rental cost of the DID, empty when the cost of its provider is not configured.
+			monthlyCostStr := ""
+			if monthlyCost, found := didsMonthlyCostByProvider[oneResult.ProviderID]; found {
+				monthlyCostStr = formatPrice(monthlyCost)
+				totalMonthlyCost += monthlyCost
+			}
+
+			// Write the formatted result to the output file.
+			fmt.Fprintf(outputFile, "%s;%s;%s;%s;%s;%s;%d;%s;%s;%s\n", oneResult.Did, oneResult.Provider, oneResult.Username, extensionStr, descriptionStr, closedTillStr, oneResult.Calls, lastCallStr, idleDaysStr, monthlyCostStr)
+		}
+
+		// Write the total monthly cost of the exported DIDs.
+		fmt.Fprintf(outputFile, "Total;;;;;;;;;%s\n", formatPrice(totalMonthlyCost))
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	},
+}
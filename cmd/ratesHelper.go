@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ModelMorRate represents a rate detail of a destination prefix of a MOR tariff.
+type ModelMorRate struct {
+	TariffID      string
+	Tariff        string
+	Prefix        string
+	Rate          float64
+	ConnectionFee float64
+	Increment     int
+	MinTime       int
+	StartTime     string
+	EndTime       string
+	DayType       string
+}
+
+// rateDeck represents the rates of a tariff by destination prefix.
+type rateDeck map[string][]ModelMorRate
+
+// Retrieve rate data from the database and return it as a slice of models.
+func getModelMorRate(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorRate
+
+		if err := rows.Scan(
+			&msg.TariffID,
+			&msg.Tariff,
+			&msg.Prefix,
+			&msg.Rate,
+			&msg.ConnectionFee,
+			&msg.Increment,
+			&msg.MinTime,
+			&msg.StartTime,
+			&msg.EndTime,
+			&msg.DayType,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Load the rate decks of the tariffs from the MOR database by tariff ID. The wholesale tariffs (provider and user wholesale purposes)
+// are rated by destination with their rate details. The retail tariffs (user purpose) are rated by destination group with their
+// first minute range, rounded to their minutes, and their event prices as connection fee.
+func getMorRateDecks() (map[string]rateDeck, error) {
+	// Construct the SQL query of the wholesale and retail rates.
+	request := `SELECT
+			t.id AS TariffID,
+			t.name AS Tariff,
+			d.prefix AS Prefix,
+			rd.rate AS Rate,
+			IFNULL(rd.connection_fee, 0) AS ConnectionFee,
+			IFNULL(rd.increment_s, 1) AS Increment,
+			IFNULL(rd.min_time, 0) AS MinTime,
+			IFNULL(rd.start_time, '00:00:00') AS StartTime,
+			IFNULL(rd.end_time, '23:59:59') AS EndTime,
+			IFNULL(rd.daytype, '') AS DayType
+		FROM mor.tariffs t
+		INNER JOIN mor.rates r ON r.tariff_id = t.id
+		INNER JOIN mor.destinations d ON r.destination_id = d.id
+		INNER JOIN mor.ratedetails rd ON rd.rate_id = r.id
+		WHERE t.purpose IN ('provider', 'user_wholesale')
+		UNION ALL
+		SELECT
+			t.id AS TariffID,
+			t.name AS Tariff,
+			d.prefix AS Prefix,
+			SUM(CASE WHEN ard.artype = 'minute' AND ard.from <= 1 THEN ard.price ELSE 0 END) AS Rate,
+			SUM(CASE WHEN ard.artype = 'event' THEN ard.price ELSE 0 END) AS ConnectionFee,
+			IFNULL(MAX(CASE WHEN ard.artype = 'minute' AND ard.from <= 1 THEN ard.round END), 1) * 60 AS Increment,
+			0 AS MinTime,
+			IFNULL(ard.start_time, '00:00:00') AS StartTime,
+			IFNULL(ard.end_time, '23:59:59') AS EndTime,
+			IFNULL(ard.daytype, '') AS DayType
+		FROM mor.tariffs t
+		INNER JOIN mor.rates r ON r.tariff_id = t.id
+		INNER JOIN mor.destinations d ON d.destinationgroup_id = r.destinationgroup_id
+		INNER JOIN mor.aratedetails ard ON ard.rate_id = r.id
+		WHERE t.purpose = 'user'
+		GROUP BY t.id, t.name, d.prefix, ard.start_time, ard.end_time, ard.daytype;`
+
+	// Log the SQL query for debugging and tracking purposes.
+	log.Print(request)
+
+	// Send the SQL request to the MorRequest function and obtain results.
+	results, err := MorRequest(request, getModelMorRate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the rates by tariff and by prefix.
+	rateDecks := make(map[string]rateDeck)
+	for _, result := range results {
+		rate := result.(ModelMorRate)
+		if rateDecks[rate.TariffID] == nil {
+			rateDecks[rate.TariffID] = make(rateDeck)
+		}
+		rateDecks[rate.TariffID][rate.Prefix] = append(rateDecks[rate.TariffID][rate.Prefix], rate)
+	}
+
+	return rateDecks, nil
+}
+
+// Find the rate of the longest prefix of the number matching the rate deck, for the time and the day type of the call. The rate is
+// not found when no rate detail of this prefix matches the time of the call, use hasPrefix to tell it from a missing prefix.
+func (deck rateDeck) findRate(number string, callDate time.Time) (ModelMorRate, bool) {
+	// Weekends are free days (FD), the other days are work days (WD).
+	dayType := "WD"
+	if callDate.Weekday() == time.Saturday || callDate.Weekday() == time.Sunday {
+		dayType = "FD"
+	}
+	callTime := callDate.Format("15:04:05")
+
+	for length := len(number); length > 0; length-- {
+		rates, found := deck[number[:length]]
+		if !found {
+			continue
+		}
+		for _, rate := range rates {
+			if (rate.DayType == "" || rate.DayType == dayType) && rate.StartTime <= callTime && callTime <= rate.EndTime {
+				return rate, true
+			}
+		}
+		return ModelMorRate{}, false
+	}
+
+	return ModelMorRate{}, false
+}
+
+// Check if a prefix of the number is in the rate deck, whatever the time of its rate details.
+func (deck rateDeck) hasPrefix(number string) bool {
+	for length := len(number); length > 0; length-- {
+		if _, found := deck[number[:length]]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// Compute the price of a call: the billed seconds are at least the minimum time, rounded up to the increment, plus the connection fee.
+func computeCallPrice(rate ModelMorRate, billsec int) float64 {
+	if billsec <= 0 {
+		return 0
+	}
+
+	billedSeconds := billsec
+	if billedSeconds < rate.MinTime {
+		billedSeconds = rate.MinTime
+	}
+	if rate.Increment > 1 {
+		billedSeconds = int(math.Ceil(float64(billedSeconds)/float64(rate.Increment))) * rate.Increment
+	}
+
+	return rate.Rate*float64(billedSeconds)/60 + rate.ConnectionFee
+}
+
+// ModelMorProviderTariff represents a provider with its tariff.
+type ModelMorProviderTariff struct {
+	ID       string
+	Name     string
+	TariffID string
+}
+
+// Retrieve provider data from the database and return it as a slice of models.
+func getModelMorProviderTariff(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorProviderTariff
+
+		if err := rows.Scan(
+			&msg.ID,
+			&msg.Name,
+			&msg.TariffID,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Load the providers of the providersID list with their tariff, in the order of their ID.
+func getMorProvidersTariffs() ([]ModelMorProviderTariff, error) {
+	// Construct the SQL query of the providers.
+	request := fmt.Sprintf(`SELECT
+			p.id AS ID,
+			p.name AS Name,
+			IFNULL(p.tariff_id, 0) AS TariffID
+		FROM mor.providers p
+		WHERE p.id IN (%s)
+		ORDER BY p.id;`, strings.Join(providersID, ","))
+
+	// Log the SQL query for debugging and tracking purposes.
+	log.Print(request)
+
+	// Send the SQL request to the MorRequest function and obtain results.
+	results, err := MorRequest(request, getModelMorProviderTariff)
+	if err != nil {
+		return nil, err
+	}
+
+	var providers []ModelMorProviderTariff
+	for _, result := range results {
+		providers = append(providers, result.(ModelMorProviderTariff))
+	}
+
+	return providers, nil
+}
+
+// Read a rate deck from a CSV file separated by semicolons, with a header row naming the columns Prefix and Rate (per minute)
+// and optionally Connection fee, Increment and Min time. The rates apply at any time of any day.
+func readRateDeckFile(filename string, tariff string) (rateDeck, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	// Find the index of each column in the header row.
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	indexes := map[string]int{"prefix": -1, "rate": -1, "connection fee": -1, "increment": -1, "min time": -1}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, found := indexes[name]; found {
+			indexes[name] = i
+		}
+	}
+	if indexes["prefix"] < 0 || indexes["rate"] < 0 {
+		return nil, fmt.Errorf("columns Prefix and Rate not found in the header of %s", filename)
+	}
+
+	// Parse each rate of the file, the numbers can use a decimal comma.
+	getValue := func(record []string, column string) (float64, error) {
+		if indexes[column] < 0 || indexes[column] >= len(record) || strings.TrimSpace(record[indexes[column]]) == "" {
+			return 0, nil
+		}
+		return strconv.ParseFloat(strings.Replace(strings.TrimSpace(record[indexes[column]]), ",", ".", 1), 64)
+	}
+	deck := make(rateDeck)
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, err
+		}
+
+		rate := ModelMorRate{Tariff: tariff, StartTime: "00:00:00", EndTime: "23:59:59", Increment: 1}
+		if indexes["prefix"] < len(record) {
+			rate.Prefix = strings.TrimLeft(strings.TrimSpace(record[indexes["prefix"]]), "+")
+		}
+		if rate.Prefix == "" || strings.Trim(rate.Prefix, "0123456789") != "" {
+			return nil, fmt.Errorf("line %d of %s: invalid prefix %s", line, filename, rate.Prefix)
+		}
+		var increment, minTime float64
+		if rate.Rate, err = getValue(record, "rate"); err != nil {
+			return nil, fmt.Errorf("line %d of %s: invalid rate", line, filename)
+		}
+		if rate.ConnectionFee, err = getValue(record, "connection fee"); err != nil {
+			return nil, fmt.Errorf("line %d of %s: invalid connection fee", line, filename)
+		}
+		if increment, err = getValue(record, "increment"); err != nil {
+			return nil, fmt.Errorf("line %d of %s: invalid increment", line, filename)
+		}
+		if minTime, err = getValue(record, "min time"); err != nil {
+			return nil, fmt.Errorf("line %d of %s: invalid min time", line, filename)
+		}
+		if increment > 1 {
+			rate.Increment = int(increment)
+		}
+		rate.MinTime = int(minTime)
+
+		deck[rate.Prefix] = append(deck[rate.Prefix], rate)
+	}
+
+	return deck, nil
+}
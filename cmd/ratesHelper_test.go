@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindRate(t *testing.T) {
+	deck := rateDeck{
+		"33":  {{Prefix: "33", Rate: 0.01, StartTime: "00:00:00", EndTime: "23:59:59"}},
+		"336": {{Prefix: "336", Rate: 0.10, StartTime: "08:00:00", EndTime: "19:59:59", DayType: "WD"}, {Prefix: "336", Rate: 0.05, StartTime: "00:00:00", EndTime: "23:59:59", DayType: "FD"}},
+	}
+
+	tests := []struct {
+		name          string
+		number        string
+		callDate      string
+		wantFound     bool
+		wantRate      float64
+		wantHasPrefix bool
+	}{
+		{"longest prefix on a work day", "33612345678", "2023-01-02 10:00:00", true, 0.10, true},
+		{"longest prefix on a free day", "33612345678", "2023-01-07 22:00:00", true, 0.05, true},
+		{"shorter prefix", "33123456789", "2023-01-02 22:00:00", true, 0.01, true},
+		{"no rate detail for the time", "33612345678", "2023-01-02 22:00:00", false, 0, true},
+		{"missing prefix", "44123456789", "2023-01-02 10:00:00", false, 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			callDate, _ := time.Parse("2006-01-02 15:04:05", test.callDate)
+			rate, found := deck.findRate(test.number, callDate)
+			if found != test.wantFound || rate.Rate != test.wantRate {
+				t.Errorf("findRate(%s, %s) = %g, %v, want %g, %v", test.number, test.callDate, rate.Rate, found, test.wantRate, test.wantFound)
+			}
+			if hasPrefix := deck.hasPrefix(test.number); hasPrefix != test.wantHasPrefix {
+				t.Errorf("hasPrefix(%s) = %v, want %v", test.number, hasPrefix, test.wantHasPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,450 @@
+package cmd
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+	reconcileCmd.Flags().StringP("file", "f", "", "The CDR file sent by the provider")
+	reconcileCmd.Flags().StringP("provider", "p", "", "The provider ID of the CDR file")
+	reconcileCmd.Flags().StringP("dateStart", "s", "", "The start date of the reconciliation")
+	reconcileCmd.Flags().StringP("dateEnd", "e", "", "The end date of the reconciliation")
+	reconcileCmd.Flags().IntP("timeTolerance", "t", 10, "The maximum difference in seconds between the call dates of matching calls")
+	reconcileCmd.Flags().IntP("durationTolerance", "d", 1, "The maximum difference in seconds between the durations of matching calls")
+	reconcileCmd.Flags().Float64P("priceTolerance", "m", 0.01, "The maximum difference between the prices of matching calls")
+}
+
+// providerCdrMapping represents the layout of the CDR file of a provider: the separator, the date layout and the header names of the columns.
+// The Src and Price columns are optional, the source numbers and the prices are not compared when they are empty.
+type providerCdrMapping struct {
+	Separator  rune
+	DateLayout string
+	CallDate   string
+	Src        string
+	Dst        string
+	Duration   string
+	Price      string
+}
+
+// Statuses of the reconciled calls, in the order of the totals.
+var reconcileStatuses = []string{"MATCHED", "DURATION MISMATCH", "PRICE MISMATCH", "PROVIDER ONLY", "MOR ONLY"}
+
+// ModelReconcileCall represents a call of the provider CDR file or of the MOR database.
+type ModelReconcileCall struct {
+	ID       string
+	CallDate string
+	Src      string
+	Dst      string
+	Billsec  int
+	Price    float64
+	date     time.Time
+	matched  bool
+}
+
+// reconcileTotal represents the totals of the calls of a status.
+type reconcileTotal struct {
+	calls            int
+	providerDuration int
+	morBillsec       int
+	providerPrice    float64
+	morPrice         float64
+}
+
+// Retrieve call data from the database and return it as a slice of models.
+func getModelReconcileCall(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelReconcileCall
+
+		if err := rows.Scan(
+			&msg.ID,
+			&msg.CallDate,
+			&msg.Src,
+			&msg.Dst,
+			&msg.Billsec,
+			&msg.Price,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Read the mapping of the CDR file of a provider from the RECONCILE_<provider_id>_* keys of the configuration file.
+func getProviderCdrMapping(providerID string) (providerCdrMapping, error) {
+	prefix := "RECONCILE_" + providerID + "_"
+	mapping := providerCdrMapping{
+		Separator:  ';',
+		DateLayout: viper.GetString(prefix + "DATE_LAYOUT"),
+		CallDate:   viper.GetString(prefix + "CALLDATE"),
+		Src:        viper.GetString(prefix + "SRC"),
+		Dst:        viper.GetString(prefix + "DST"),
+		Duration:   viper.GetString(prefix + "DURATION"),
+		Price:      viper.GetString(prefix + "PRICE"),
+	}
+	if mapping.CallDate == "" || mapping.Dst == "" || mapping.Duration == "" {
+		return mapping, fmt.Errorf("%sCALLDATE, %sDST and %sDURATION must be set in the configuration file", prefix, prefix, prefix)
+	}
+	if mapping.DateLayout == "" {
+		mapping.DateLayout = "2006-01-02 15:04:05"
+	}
+
+	// The separator is given by name or as a single character, semicolon by default.
+	switch separator := viper.GetString(prefix + "SEPARATOR"); separator {
+	case "", "semicolon":
+	case "comma":
+		mapping.Separator = ','
+	case "tab":
+		mapping.Separator = '\t'
+	case "pipe":
+		mapping.Separator = '|'
+	default:
+		runes := []rune(separator)
+		if len(runes) != 1 {
+			return mapping, fmt.Errorf("invalid %sSEPARATOR %s, use semicolon, comma, tab, pipe or a single character", prefix, separator)
+		}
+		mapping.Separator = runes[0]
+	}
+
+	return mapping, nil
+}
+
+// Build the key of a number to match the provider and MOR formats: its last 9 digits.
+func getNumberKey(number string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+	if len(digits) > 9 {
+		digits = digits[len(digits)-9:]
+	}
+	return digits
+}
+
+// Read the calls of a provider CDR file with the mapping of its columns.
+func readProviderCdrFile(filename string, mapping providerCdrMapping) ([]ModelReconcileCall, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = mapping.Separator
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	// Find the index of each mapped column in the header row.
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	indexes := make(map[string]int)
+	for _, column := range []string{mapping.CallDate, mapping.Src, mapping.Dst, mapping.Duration, mapping.Price} {
+		if column == "" {
+			continue
+		}
+		indexes[column] = -1
+		for i, name := range header {
+			if strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")) == column {
+				indexes[column] = i
+			}
+		}
+		if indexes[column] < 0 {
+			return nil, fmt.Errorf("column %s not found in the header of %s", column, filename)
+		}
+	}
+
+	// Parse each record of the file.
+	var calls []ModelReconcileCall
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < len(header) {
+			return nil, fmt.Errorf("line %d of %s: %d columns instead of %d", line, filename, len(record), len(header))
+		}
+
+		var call ModelReconcileCall
+		call.CallDate = strings.TrimSpace(record[indexes[mapping.CallDate]])
+		call.date, err = time.Parse(mapping.DateLayout, call.CallDate)
+		if err != nil {
+			return nil, fmt.Errorf("line %d of %s: invalid call date %s", line, filename, call.CallDate)
+		}
+		call.CallDate = call.date.Format("2006-01-02 15:04:05")
+		if mapping.Src != "" {
+			call.Src = strings.TrimSpace(record[indexes[mapping.Src]])
+		}
+		call.Dst = strings.TrimSpace(record[indexes[mapping.Dst]])
+
+		// The durations and the prices can use a decimal comma.
+		duration, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(record[indexes[mapping.Duration]]), ",", ".", 1), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d of %s: invalid duration %s", line, filename, record[indexes[mapping.Duration]])
+		}
+		call.Billsec = int(math.Round(duration))
+		if mapping.Price != "" {
+			call.Price, err = strconv.ParseFloat(strings.Replace(strings.TrimSpace(record[indexes[mapping.Price]]), ",", ".", 1), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d of %s: invalid price %s", line, filename, record[indexes[mapping.Price]])
+			}
+		}
+
+		calls = append(calls, call)
+	}
+
+	return calls, nil
+}
+
+// Define the main Cobra command for reconciling a provider CDR file with the MOR calls.
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Reconcile the CDR file of a provider with the MOR calls and export the matched, missing and mismatched calls.",
+	Long: `Import the CDR file sent by a provider, match its calls with the answered calls sent to this provider in the MOR database by call date, destination number and duration, and export each call with its reconciliation status, with the totals by status in a second file.
+
+Usage:
+  reconcile -f [file] -p [provider_id] -s [start_date] -e [end_date] [-t [time_tolerance]] [-d [duration_tolerance]] [-m [price_tolerance]]
+
+Flags:
+  -f, --file string               The CDR file sent by the provider
+  -p, --provider string           The provider ID of the CDR file, with its RECONCILE_<provider_id>_* keys in the configuration file
+  -s, --dateStart string          The start date of the reconciliation (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -e, --dateEnd string            The end date of the reconciliation (e.g., 'YYYY-MM-DD HH:mm:SS')
+  -t, --timeTolerance int         The maximum difference in seconds between the call dates of matching calls (default 10)
+  -d, --durationTolerance int     The maximum difference in seconds between the durations of matching calls (default 1)
+  -m, --priceTolerance float      The maximum difference between the prices of matching calls (default 0.01)
+
+Example:
+  reconcile -f invoice_2023_01.csv -p 561 -s "2023-01-01 00:00:00" -e "2023-01-31 23:59:59" -t 30
+
+The columns of the CDR file of each provider are defined in the configuration file by the RECONCILE_<provider_id>_SEPARATOR (semicolon, comma, tab, pipe or a single character, semicolon by default), RECONCILE_<provider_id>_DATE_LAYOUT (a Go date layout, 2006-01-02 15:04:05 by default), RECONCILE_<provider_id>_CALLDATE, RECONCILE_<provider_id>_SRC (optional), RECONCILE_<provider_id>_DST, RECONCILE_<provider_id>_DURATION and RECONCILE_<provider_id>_PRICE (optional) keys, the column names being the names of the header row of the file. A provider call matches the MOR call with the same last 9 digits of the destination number, the same last 9 digits of the source number when the file has a source column and the provider source is not empty, and the nearest call date within the time tolerance. The Status column is MATCHED, DURATION MISMATCH, PRICE MISMATCH, PROVIDER ONLY or MOR ONLY. The provider calls outside of the date range are ignored. The generated CSV files are named with a timestamp and saved in the current working directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the file, the provider, the start and end date strings and the tolerances from the command-line flags.
+		file, _ := cmd.Flags().GetString("file")
+		providerID, _ := cmd.Flags().GetString("provider")
+		dateStartStr, _ := cmd.Flags().GetString("dateStart")
+		dateEndStr, _ := cmd.Flags().GetString("dateEnd")
+		timeTolerance, _ := cmd.Flags().GetInt("timeTolerance")
+		durationTolerance, _ := cmd.Flags().GetInt("durationTolerance")
+		priceTolerance, _ := cmd.Flags().GetFloat64("priceTolerance")
+
+		// Parse the provided start and end dates.
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Retrieve the mapping of the provider CDR file.
+		if providerID == "" || strings.Trim(providerID, "0123456789") != "" {
+			fmt.Println("Invalid provider. Please use a numeric provider ID")
+			return
+		}
+		mapping, err := getProviderCdrMapping(providerID)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		// Display the file, the provider and the date information for the user's reference.
+		fmt.Println("reconcile called with file: " + file + ", provider: " + providerID + ", dateStart: " + dateStart.Format("2006-01-02 15:04:05") + " and dateEnd: " + dateEnd.Format("2006-01-02 15:04:05"))
+
+		// Read the provider calls of the date range.
+		fileCalls, err := readProviderCdrFile(file, mapping)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var providerCalls []ModelReconcileCall
+		for _, call := range fileCalls {
+			if call.date.After(dateStart) && call.date.Before(dateEnd) {
+				providerCalls = append(providerCalls, call)
+			}
+		}
+		sort.SliceStable(providerCalls, func(i, j int) bool { return providerCalls[i].date.Before(providerCalls[j].date) })
+
+		// Construct the SQL query with placeholders.
+		request := fmt.Sprintf(`SELECT
+			c.id AS ID,
+			c.calldate AS CallDate,
+			c.src AS Src,
+			c.dst AS Dst,
+			c.billsec AS Billsec,
+			IFNULL(c.provider_price, 0) AS Price
+		FROM mor.calls c
+		WHERE
+			c.calldate > '%s' AND
+			c.calldate < '%s' AND
+			c.provider_id = %s AND
+			c.disposition = 'ANSWERED'
+		ORDER BY c.calldate;`, dateStartStr, dateEndStr, providerID)
+
+		// Log the SQL query for debugging and tracking purposes.
+		log.Print(request)
+
+		// Send the SQL request to the MorRequest function and obtain results.
+		results, err := MorRequest(request, getModelReconcileCall)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Index the MOR calls by destination number.
+		morCalls := make([]ModelReconcileCall, len(results))
+		morCallsByNumber := make(map[string][]int)
+		for i, result := range results {
+			morCalls[i] = result.(ModelReconcileCall)
+			morCalls[i].date, _ = time.Parse("2006-01-02 15:04:05", morCalls[i].CallDate)
+			key := getNumberKey(morCalls[i].Dst)
+			morCallsByNumber[key] = append(morCallsByNumber[key], i)
+		}
+
+		// Generate a filename prefix for the output files.
+		now := time.Now()
+		filenamePrefix := fmt.Sprintf("%d_%02d_%02d_%02d_%02d_%02d", now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second())
+		filename := filenamePrefix + "_export.csv"
+
+		// Create and open the output file for writing.
+		outputFile, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outputFile.Close()
+
+		// Write the header row to the output file.
+		fmt.Fprintln(outputFile, "Status;Provider call date;MOR call date;Src;Dst;Provider duration;MOR billsec;Duration difference;Provider price;MOR price;Price difference;MOR call ID")
+
+		// Match each provider call with the unmatched MOR call of the same number with the nearest call date.
+		// When the file has a source column, the source numbers must match too, unless the provider source is empty (anonymous calls).
+		totals := make(map[string]*reconcileTotal)
+		for _, status := range reconcileStatuses {
+			totals[status] = &reconcileTotal{}
+		}
+		for _, providerCall := range providerCalls {
+			bestIndex := -1
+			bestDifference := time.Duration(timeTolerance+1) * time.Second
+			srcKey := getNumberKey(providerCall.Src)
+			for _, i := range morCallsByNumber[getNumberKey(providerCall.Dst)] {
+				if srcKey != "" && getNumberKey(morCalls[i].Src) != srcKey {
+					continue
+				}
+				difference := providerCall.date.Sub(morCalls[i].date)
+				if difference < 0 {
+					difference = -difference
+				}
+				if !morCalls[i].matched && difference < bestDifference {
+					bestIndex = i
+					bestDifference = difference
+				}
+			}
+
+			if bestIndex < 0 {
+				totals["PROVIDER ONLY"].calls++
+				totals["PROVIDER ONLY"].providerDuration += providerCall.Billsec
+				totals["PROVIDER ONLY"].providerPrice += providerCall.Price
+				fmt.Fprintf(outputFile, "PROVIDER ONLY;%s;;%s;%s;%d;;;%s;;;\n", providerCall.CallDate, providerCall.Src, providerCall.Dst, providerCall.Billsec, strconv.FormatFloat(providerCall.Price, 'f', 4, 64))
+				continue
+			}
+
+			// Compare the durations and the prices of the matching calls.
+			morCall := &morCalls[bestIndex]
+			morCall.matched = true
+			durationDifference := providerCall.Billsec - morCall.Billsec
+			priceDifference := providerCall.Price - morCall.Price
+			status := "MATCHED"
+			if durationDifference > durationTolerance || durationDifference < -durationTolerance {
+				status = "DURATION MISMATCH"
+			} else if mapping.Price != "" && math.Abs(priceDifference) > priceTolerance {
+				status = "PRICE MISMATCH"
+			}
+			totals[status].calls++
+			totals[status].providerDuration += providerCall.Billsec
+			totals[status].morBillsec += morCall.Billsec
+			totals[status].providerPrice += providerCall.Price
+			totals[status].morPrice += morCall.Price
+
+			// Write the formatted result to the output file.
+			fmt.Fprintf(outputFile, "%s;%s;%s;%s;%s;%d;%d;%d;%s;%s;%s;%s\n", status, providerCall.CallDate, morCall.CallDate, providerCall.Src, providerCall.Dst, providerCall.Billsec, morCall.Billsec, durationDifference, strconv.FormatFloat(providerCall.Price, 'f', 4, 64), strconv.FormatFloat(morCall.Price, 'f', 4, 64), strconv.FormatFloat(priceDifference, 'f', 4, 64), morCall.ID)
+		}
+
+		// Write the MOR calls missing from the provider file.
+		for _, morCall := range morCalls {
+			if morCall.matched {
+				continue
+			}
+			totals["MOR ONLY"].calls++
+			totals["MOR ONLY"].morBillsec += morCall.Billsec
+			totals["MOR ONLY"].morPrice += morCall.Price
+			fmt.Fprintf(outputFile, "MOR ONLY;;%s;%s;%s;;%d;;;%s;;%s\n", morCall.CallDate, morCall.Src, morCall.Dst, morCall.Billsec, strconv.FormatFloat(morCall.Price, 'f', 4, 64), morCall.ID)
+		}
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+
+		// Create and open the totals file for writing.
+		totalsFilename := filenamePrefix + "_totals.csv"
+		totalsFile, err := os.Create(totalsFilename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer totalsFile.Close()
+
+		// Write the totals by status and the grand total, to the file and to the terminal.
+		fmt.Fprintln(totalsFile, "Status;Calls;Provider duration;MOR billsec;Provider price;MOR price;Price difference")
+		grandTotal := reconcileTotal{}
+		for _, status := range reconcileStatuses {
+			total := totals[status]
+			grandTotal.calls += total.calls
+			grandTotal.providerDuration += total.providerDuration
+			grandTotal.morBillsec += total.morBillsec
+			grandTotal.providerPrice += total.providerPrice
+			grandTotal.morPrice += total.morPrice
+			fmt.Fprintf(totalsFile, "%s;%d;%d;%d;%s;%s;%s\n", status, total.calls, total.providerDuration, total.morBillsec, formatPrice(total.providerPrice), formatPrice(total.morPrice), formatPrice(total.providerPrice-total.morPrice))
+			fmt.Printf("%s: %d calls\n", status, total.calls)
+		}
+		fmt.Fprintf(totalsFile, "Total;%d;%d;%d;%s;%s;%s\n", grandTotal.calls, grandTotal.providerDuration, grandTotal.morBillsec, formatPrice(grandTotal.providerPrice), formatPrice(grandTotal.morPrice), formatPrice(grandTotal.providerPrice-grandTotal.morPrice))
+		fmt.Printf("Provider price %s, MOR price %s, difference %s\n", formatPrice(grandTotal.providerPrice), formatPrice(grandTotal.morPrice), formatPrice(grandTotal.providerPrice-grandTotal.morPrice))
+
+		// Log a message indicating the filename of the exported totals.
+		log.Printf("%s exported", totalsFilename)
+	},
+}
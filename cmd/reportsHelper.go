@@ -0,0 +1,403 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// reportDefinition represents a report exported for a date range: its command, the first columns of its header identifying its exports
+// and the key columns aligning its rows between two exports.
+type reportDefinition struct {
+	cmd          *cobra.Command
+	headerPrefix string
+	keyColumns   []string
+}
+
+// Reports by command name.
+var reports = make(map[string]reportDefinition)
+
+// reportRun represents the period and the export file of a run of a report, given by the wrappers of its command (the period
+// comparison and the incremental export) instead of the dateStart and dateEnd flags and of the timestamped filename.
+type reportRun struct {
+	dateStart string
+	dateEnd   string
+	filename  string
+}
+
+// Key of the report run in the context of the command.
+type reportRunKey struct{}
+
+// Run a report for a period with an export file.
+func runReport(run func(*cobra.Command, []string), cmd *cobra.Command, args []string, period reportRun) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd.SetContext(context.WithValue(ctx, reportRunKey{}, period))
+	defer cmd.SetContext(ctx)
+	run(cmd, args)
+}
+
+// Retrieve the period of a report: the period of the run, the dateStart and dateEnd flags otherwise.
+func getReportPeriod(cmd *cobra.Command) (string, string) {
+	if cmd.Context() != nil {
+		if period, ok := cmd.Context().Value(reportRunKey{}).(reportRun); ok {
+			return period.dateStart, period.dateEnd
+		}
+	}
+	dateStart, _ := cmd.Flags().GetString("dateStart")
+	dateEnd, _ := cmd.Flags().GetString("dateEnd")
+	return dateStart, dateEnd
+}
+
+// Generate the name of the export file of a report: the export file of the run, a timestamped name otherwise.
+func getExportFilename(cmd *cobra.Command) string {
+	if cmd.Context() != nil {
+		if period, ok := cmd.Context().Value(reportRunKey{}).(reportRun); ok && period.filename != "" {
+			return period.filename
+		}
+	}
+	now := time.Now()
+	return fmt.Sprintf("%d_%02d_%02d_%02d_%02d_%02d_export.csv", now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second())
+}
+
+// Register a report with the first columns of its header and its key columns, and add the comparison option to its command.
+func registerReport(cmd *cobra.Command, headerPrefix string, keyColumns ...string) {
+	reports[cmd.Name()] = reportDefinition{cmd: cmd, headerPrefix: headerPrefix, keyColumns: keyColumns}
+	cmd.Flags().String("compare", "", "Compare with another period: previous, year-ago or 'YYYY-MM-DD HH:mm:SS,YYYY-MM-DD HH:mm:SS'")
+
+	// Run the report for both periods and compare the exports when the option is given.
+	run := cmd.Run
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		compare, _ := cmd.Flags().GetString("compare")
+		if compare == "" {
+			run(cmd, args)
+			return
+		}
+
+		// Compute the compared period from the period of the report.
+		dateStartStr, dateEndStr := getReportPeriod(cmd)
+		dateStart, err := time.Parse("2006-01-02 15:04:05", dateStartStr)
+		if err != nil {
+			fmt.Println("Invalid dateStart format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+		dateEnd, err := time.Parse("2006-01-02 15:04:05", dateEndStr)
+		if err != nil {
+			fmt.Println("Invalid dateEnd format. Please use 'YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+		previousStart, previousEnd, err := getComparedPeriod(compare, dateStart, dateEnd)
+		if err != nil {
+			fmt.Println("Invalid compare. Please use previous, year-ago or 'YYYY-MM-DD HH:mm:SS,YYYY-MM-DD HH:mm:SS'")
+			return
+		}
+
+		// Generate a filename prefix for the output files.
+		now := time.Now()
+		filenamePrefix := fmt.Sprintf("%d_%02d_%02d_%02d_%02d_%02d", now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second())
+		currentFilename := filenamePrefix + "_export.csv"
+		previousFilename := filenamePrefix + "_previous_export.csv"
+
+		// Export the report for the period and for the compared period.
+		runReport(run, cmd, args, reportRun{dateStart: dateStartStr, dateEnd: dateEndStr, filename: currentFilename})
+		runReport(run, cmd, args, reportRun{dateStart: previousStart.Format("2006-01-02 15:04:05"), dateEnd: previousEnd.Format("2006-01-02 15:04:05"), filename: previousFilename})
+
+		// Skip the comparison when a period was not exported (e.g., an invalid option of the report).
+		for _, exportedFilename := range []string{currentFilename, previousFilename} {
+			if _, err := os.Stat(exportedFilename); err != nil {
+				fmt.Printf("%s was not exported, the periods are not compared\n", exportedFilename)
+				return
+			}
+		}
+
+		// Compare the exports, the days are aligned on their position in the periods.
+		filename := filenamePrefix + "_compare.csv"
+		_, err = compareExportFiles(currentFilename, previousFilename, filename, reports[cmd.Name()].keyColumns, dateStart, previousStart, false)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Log a message indicating the filename of the exported data.
+		log.Printf("%s exported", filename)
+	}
+}
+
+// Compute the compared period: the previous period of the same length (the previous months for whole months), the same period a year ago or a given range.
+func getComparedPeriod(compare string, dateStart time.Time, dateEnd time.Time) (time.Time, time.Time, error) {
+	switch compare {
+	case "previous":
+		// Whole months are compared with the same number of months.
+		nextSecond := dateEnd.Add(time.Second)
+		if dateStart.Day() == 1 && dateStart.Format("15:04:05") == "00:00:00" && nextSecond.Day() == 1 && nextSecond.Format("15:04:05") == "00:00:00" {
+			months := (nextSecond.Year()-dateStart.Year())*12 + int(nextSecond.Month()-dateStart.Month())
+			return dateStart.AddDate(0, -months, 0), dateStart.Add(-time.Second), nil
+		}
+		return dateStart.Add(-time.Second - dateEnd.Sub(dateStart)), dateStart.Add(-time.Second), nil
+	case "year-ago":
+		return dateStart.AddDate(-1, 0, 0), dateEnd.AddDate(-1, 0, 0), nil
+	}
+
+	dates := strings.Split(compare, ",")
+	if len(dates) != 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid compare %s", compare)
+	}
+	previousStart, err := time.Parse("2006-01-02 15:04:05", strings.TrimSpace(dates[0]))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	previousEnd, err := time.Parse("2006-01-02 15:04:05", strings.TrimSpace(dates[1]))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return previousStart, previousEnd, nil
+}
+
+// exportFile represents the rows of an export file by key.
+type exportFile struct {
+	header []string
+	keys   []string
+	rows   map[string][]string
+}
+
+// Read an export file and index its rows by the values of the key columns.
+// The Day key column is replaced by the number of the day in the period when a period start is given.
+func readExportFile(filename string, keyColumns []string, periodStart time.Time) (*exportFile, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty export file %s", filename)
+	}
+
+	// Find the index of each key column.
+	export := &exportFile{header: records[0], rows: make(map[string][]string)}
+	var keyIndexes []int
+	for _, column := range keyColumns {
+		index := getColumnIndex(export.header, column)
+		if index < 0 {
+			return nil, fmt.Errorf("key column %s not found in %s", column, filename)
+		}
+		keyIndexes = append(keyIndexes, index)
+	}
+
+	// Index the rows, the duplicated keys are numbered.
+	for _, record := range records[1:] {
+		var keyValues []string
+		for _, index := range keyIndexes {
+			value := ""
+			if index < len(record) {
+				value = record[index]
+			}
+			if day, err := time.Parse("2006-01-02", value); err == nil && export.header[index] == "Day" && !periodStart.IsZero() {
+				periodDay := time.Date(periodStart.Year(), periodStart.Month(), periodStart.Day(), 0, 0, 0, 0, time.UTC)
+				value = fmt.Sprintf("Day %d", int(day.Sub(periodDay).Hours()/24)+1)
+			}
+			keyValues = append(keyValues, value)
+		}
+		key := strings.Join(keyValues, ";")
+		for i := 2; export.rows[key] != nil; i++ {
+			key = fmt.Sprintf("%s;#%d", strings.Join(keyValues, ";"), i)
+		}
+		export.keys = append(export.keys, key)
+		export.rows[key] = record
+	}
+
+	return export, nil
+}
+
+// Parse a numeric value of an export, with a decimal point or a decimal comma.
+func parseExportNumber(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(value), ",", ".", 1), 64)
+	return number, err == nil
+}
+
+// Compare two exports of a report aligned on the key columns, and write side by side the values of both exports,
+// the absolute and percentage deltas of the numeric columns, and the status of the rows (NEW, REMOVED or CHANGED).
+// The unchanged rows are skipped when onlyChanges is true. The counts of the rows by status are returned.
+func compareExportFiles(currentFilename string, previousFilename string, filename string, keyColumns []string, currentStart time.Time, previousStart time.Time, onlyChanges bool) (map[string]int, error) {
+	current, err := readExportFile(currentFilename, keyColumns, currentStart)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := readExportFile(previousFilename, keyColumns, previousStart)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the value columns and check if they are numeric in both exports.
+	isKey := make(map[string]bool)
+	for _, column := range keyColumns {
+		isKey[column] = true
+	}
+	var valueColumns []string
+	for _, column := range current.header {
+		if !isKey[column] {
+			valueColumns = append(valueColumns, column)
+		}
+	}
+	isNumeric := make(map[string]bool)
+	for _, column := range valueColumns {
+		isNumeric[column] = true
+		values := 0
+		for _, export := range []*exportFile{current, previous} {
+			index := getColumnIndex(export.header, column)
+			for _, row := range export.rows {
+				if index < 0 || index >= len(row) || row[index] == "" {
+					continue
+				}
+				if _, ok := parseExportNumber(row[index]); !ok {
+					isNumeric[column] = false
+				}
+				values++
+			}
+		}
+		isNumeric[column] = isNumeric[column] && values > 0
+	}
+
+	// Create and open the output file for writing.
+	outputFile, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer outputFile.Close()
+
+	// Write the header row to the output file.
+	header := append([]string{"Status"}, keyColumns...)
+	for _, column := range valueColumns {
+		header = append(header, column, column+" (previous)")
+		if isNumeric[column] {
+			header = append(header, column+" (delta)", column+" (delta %)")
+		}
+	}
+	fmt.Fprintln(outputFile, strings.Join(header, ";"))
+
+	// Write the rows of the current export, then the removed rows of the previous export.
+	statuses := make(map[string]int)
+	keys := append([]string{}, current.keys...)
+	var removedKeys []string
+	for _, key := range previous.keys {
+		if current.rows[key] == nil {
+			removedKeys = append(removedKeys, key)
+		}
+	}
+	keys = append(keys, removedKeys...)
+	for _, key := range keys {
+		currentRow := current.rows[key]
+		previousRow := previous.rows[key]
+
+		status := "NEW"
+		keyRow := currentRow
+		keyHeader := current.header
+		if currentRow == nil {
+			status = "REMOVED"
+			keyRow = previousRow
+			keyHeader = previous.header
+		} else if previousRow != nil {
+			status = ""
+		}
+
+		line := []string{""}
+		for _, column := range keyColumns {
+			line = append(line, getColumnValue(keyHeader, keyRow, column))
+		}
+		for _, column := range valueColumns {
+			currentValue := getColumnValue(current.header, currentRow, column)
+			previousValue := getColumnValue(previous.header, previousRow, column)
+			line = append(line, currentValue, previousValue)
+			if currentValue != previousValue && status == "" {
+				status = "CHANGED"
+			}
+			if isNumeric[column] {
+				delta, deltaPercent := formatExportDelta(currentValue, previousValue)
+				line = append(line, delta, deltaPercent)
+			}
+		}
+		statuses[status]++
+		if onlyChanges && status == "" {
+			continue
+		}
+		line[0] = status
+		fmt.Fprintln(outputFile, strings.Join(line, ";"))
+	}
+
+	return statuses, nil
+}
+
+// Retrieve the index of a column in a header, -1 if it is missing.
+func getColumnIndex(header []string, column string) int {
+	for i, name := range header {
+		if name == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// Retrieve the value of a column of a row, empty if the row or the column is missing.
+func getColumnValue(header []string, row []string, column string) string {
+	index := getColumnIndex(header, column)
+	if row == nil || index < 0 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
+
+// Format the absolute and percentage deltas between two numeric values, a missing value counts as 0.
+func formatExportDelta(currentValue string, previousValue string) (string, string) {
+	current, _ := parseExportNumber(currentValue)
+	previous, _ := parseExportNumber(previousValue)
+	delta := current - previous
+
+	// Keep the format of the values: integers, decimal point or decimal comma.
+	formattedDelta := strconv.FormatFloat(delta, 'f', 2, 64)
+	if !strings.ContainsAny(currentValue+previousValue, ".,") {
+		formattedDelta = strconv.FormatFloat(math.Round(delta), 'f', 0, 64)
+	} else if strings.Contains(currentValue+previousValue, ",") {
+		formattedDelta = strings.Replace(formattedDelta, ".", ",", 1)
+	}
+
+	formattedDeltaPercent := ""
+	if previous != 0 {
+		formattedDeltaPercent = strconv.FormatFloat(delta*100/math.Abs(previous), 'f', 2, 64)
+	}
+
+	return formattedDelta, formattedDeltaPercent
+}
+
+// Find the report of an export from its header: the report with the longest header prefix matching the header.
+func findReportOfHeader(header []string) (string, bool) {
+	headerLine := strings.Join(header, ";") + ";"
+
+	found := ""
+	for name, definition := range reports {
+		if !strings.HasPrefix(headerLine, definition.headerPrefix+";") {
+			continue
+		}
+		if found == "" || len(definition.headerPrefix) > len(reports[found].headerPrefix) {
+			found = name
+		}
+	}
+
+	return found, found != ""
+}
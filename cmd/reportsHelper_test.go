@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetComparedPeriod(t *testing.T) {
+	tests := []struct {
+		name      string
+		compare   string
+		dateStart string
+		dateEnd   string
+		wantStart string
+		wantEnd   string
+	}{
+		{"previous month", "previous", "2023-03-01 00:00:00", "2023-03-31 23:59:59", "2023-02-01 00:00:00", "2023-02-28 23:59:59"},
+		{"previous month across a year", "previous", "2023-01-01 00:00:00", "2023-01-31 23:59:59", "2022-12-01 00:00:00", "2022-12-31 23:59:59"},
+		{"previous months", "previous", "2023-04-01 00:00:00", "2023-06-30 23:59:59", "2023-01-01 00:00:00", "2023-03-31 23:59:59"},
+		{"previous week", "previous", "2023-03-13 00:00:00", "2023-03-19 23:59:59", "2023-03-06 00:00:00", "2023-03-12 23:59:59"},
+		{"previous partial month", "previous", "2023-03-01 00:00:00", "2023-03-15 23:59:59", "2023-02-14 00:00:00", "2023-02-28 23:59:59"},
+		{"year ago", "year-ago", "2023-03-01 00:00:00", "2023-03-31 23:59:59", "2022-03-01 00:00:00", "2022-03-31 23:59:59"},
+		{"given range", "2022-12-01 00:00:00, 2022-12-15 23:59:59", "2023-03-01 00:00:00", "2023-03-31 23:59:59", "2022-12-01 00:00:00", "2022-12-15 23:59:59"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dateStart, _ := time.Parse("2006-01-02 15:04:05", test.dateStart)
+			dateEnd, _ := time.Parse("2006-01-02 15:04:05", test.dateEnd)
+			start, end, err := getComparedPeriod(test.compare, dateStart, dateEnd)
+			if err != nil {
+				t.Fatalf("getComparedPeriod returned %v", err)
+			}
+			if start.Format("2006-01-02 15:04:05") != test.wantStart || end.Format("2006-01-02 15:04:05") != test.wantEnd {
+				t.Errorf("getComparedPeriod = %s, %s, want %s, %s", start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02 15:04:05"), test.wantStart, test.wantEnd)
+			}
+		})
+	}
+
+	for _, compare := range []string{"", "next", "2022-12-01 00:00:00", "2022-12-01,2022-12-15", "2022-12-01 00:00:00,2022-12-15 23:59:59,2022-12-31 23:59:59"} {
+		if _, _, err := getComparedPeriod(compare, time.Now(), time.Now()); err == nil {
+			t.Errorf("getComparedPeriod(%q) returned no error", compare)
+		}
+	}
+}
+
+func TestCompareExportFiles(t *testing.T) {
+	dir := t.TempDir()
+	currentFilename := filepath.Join(dir, "current.csv")
+	previousFilename := filepath.Join(dir, "previous.csv")
+	filename := filepath.Join(dir, "compare.csv")
+	current := "Day;Country;Calls;Price;Provider\n2023-03-01;France;10;1,50;A\n2023-03-01;Spain;5;0,50;B\n2023-03-02;France;4;0,40;A\n"
+	previous := "Day;Country;Calls;Price;Provider\n2023-02-01;France;8;1,00;A\n2023-02-01;Spain;5;0,50;B\n2023-02-01;Italy;3;0,30;C\n2023-02-02;France;4;0,40;A\n"
+	if err := os.WriteFile(currentFilename, []byte(current), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(previousFilename, []byte(previous), 0644); err != nil {
+		t.Fatal(err)
+	}
+	currentStart := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	previousStart := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	// The days are aligned on their number in the periods, and written with the date of their export.
+	tests := []struct {
+		name         string
+		onlyChanges  bool
+		wantStatuses map[string]int
+		wantLines    []string
+	}{
+		{
+			name:         "all rows",
+			onlyChanges:  false,
+			wantStatuses: map[string]int{"CHANGED": 1, "": 2, "REMOVED": 1},
+			wantLines: []string{
+				"Status;Day;Country;Calls;Calls (previous);Calls (delta);Calls (delta %);Price;Price (previous);Price (delta);Price (delta %);Provider;Provider (previous)",
+				"CHANGED;2023-03-01;France;10;8;2;25.00;1,50;1,00;0,50;50.00;A;A",
+				";2023-03-01;Spain;5;5;0;0.00;0,50;0,50;0,00;0.00;B;B",
+				";2023-03-02;France;4;4;0;0.00;0,40;0,40;0,00;0.00;A;A",
+				"REMOVED;2023-02-01;Italy;;3;-3;-100.00;;0,30;-0,30;-100.00;;C",
+			},
+		},
+		{
+			name:         "only changes",
+			onlyChanges:  true,
+			wantStatuses: map[string]int{"CHANGED": 1, "": 2, "REMOVED": 1},
+			wantLines: []string{
+				"Status;Day;Country;Calls;Calls (previous);Calls (delta);Calls (delta %);Price;Price (previous);Price (delta);Price (delta %);Provider;Provider (previous)",
+				"CHANGED;2023-03-01;France;10;8;2;25.00;1,50;1,00;0,50;50.00;A;A",
+				"REMOVED;2023-02-01;Italy;;3;-3;-100.00;;0,30;-0,30;-100.00;;C",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statuses, err := compareExportFiles(currentFilename, previousFilename, filename, []string{"Day", "Country"}, currentStart, previousStart, test.onlyChanges)
+			if err != nil {
+				t.Fatalf("compareExportFiles returned %v", err)
+			}
+			if !reflect.DeepEqual(statuses, test.wantStatuses) {
+				t.Errorf("compareExportFiles statuses = %v, want %v", statuses, test.wantStatuses)
+			}
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+			if !reflect.DeepEqual(lines, test.wantLines) {
+				t.Errorf("compareExportFiles wrote\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(test.wantLines, "\n"))
+			}
+		})
+	}
+}
+
+func TestCompareExportFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	validFilename := filepath.Join(dir, "valid.csv")
+	emptyFilename := filepath.Join(dir, "empty.csv")
+	if err := os.WriteFile(validFilename, []byte("Country;Calls\nFrance;10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(emptyFilename, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		currentFilename  string
+		previousFilename string
+		keyColumns       []string
+	}{
+		{"missing file", validFilename, filepath.Join(dir, "missing.csv"), []string{"Country"}},
+		{"empty file", emptyFilename, validFilename, []string{"Country"}},
+		{"missing key column", validFilename, validFilename, []string{"Destination"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := compareExportFiles(test.currentFilename, test.previousFilename, filepath.Join(dir, "compare.csv"), test.keyColumns, time.Time{}, time.Time{}, false); err == nil {
+				t.Error("compareExportFiles returned no error")
+			}
+		})
+	}
+}
@@ -13,6 +13,9 @@ import (
 
 var cfgFile string
 
+// Exit code of the command line and configuration errors, UNKNOWN for the monitoring checks and the budgets check.
+var errorExitCode = 1
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "kolmisoft-mor-calls-data-exporter",
@@ -23,9 +26,13 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	if cmd, _, err := rootCmd.Find(os.Args[1:]); err == nil && (cmd == checkCmd || cmd == checkBudgetsCmd || cmd.HasParent() && cmd.Parent() == checkCmd) {
+		errorExitCode = checkUnknown
+	}
+
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(errorExitCode)
 	}
 }
 
@@ -61,7 +68,7 @@ func initConfig() {
 	// If a config file is found, read it in.
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "config file .env not found")
-		os.Exit(1)
+		os.Exit(errorExitCode)
 	} else {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
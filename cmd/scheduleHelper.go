@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the months and of the days of the week in the cron expressions.
+var cronMonthsName = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+var cronWeekdaysName = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// Shortcuts of the cron expressions.
+var cronShortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// cronSchedule represents a cron expression: minute, hour, day of month, month and day of week.
+type cronSchedule struct {
+	minutes            []bool
+	hours              []bool
+	days               []bool
+	months             []bool
+	weekdays           []bool
+	daysRestricted     bool
+	weekdaysRestricted bool
+}
+
+// Parse a cron expression of 5 fields (minute hour day-of-month month day-of-week) or a shortcut (@hourly, @daily, @weekly, @monthly, @yearly).
+// The fields accept *, numbers, names, ranges (a-b), lists (a,b) and steps (*/n, a-b/n).
+func parseCronSchedule(expression string) (*cronSchedule, error) {
+	expression = strings.TrimSpace(expression)
+	if shortcut, found := cronShortcuts[strings.ToLower(expression)]; found {
+		expression = shortcut
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: 5 fields expected", expression)
+	}
+
+	schedule := &cronSchedule{}
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if schedule.days, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12, cronMonthsName); err != nil {
+		return nil, err
+	}
+	if schedule.weekdays, err = parseCronField(fields[4], 0, 7, cronWeekdaysName); err != nil {
+		return nil, err
+	}
+
+	// Sunday is 0 or 7.
+	schedule.weekdays[0] = schedule.weekdays[0] || schedule.weekdays[7]
+	schedule.daysRestricted = !strings.HasPrefix(fields[2], "*")
+	schedule.weekdaysRestricted = !strings.HasPrefix(fields[4], "*")
+
+	return schedule, nil
+}
+
+// Parse a field of a cron expression and return the matching values.
+func parseCronField(field string, min int, max int, names map[string]int) ([]bool, error) {
+	values := make([]bool, max+1)
+
+	// Convert a value of the field, a number or a name.
+	parseValue := func(value string) (int, error) {
+		if number, found := names[strings.ToLower(value)]; found {
+			return number, nil
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil || number < min || number > max {
+			return 0, fmt.Errorf("invalid value %q in cron field %q", value, field)
+		}
+		return number, nil
+	}
+
+	for _, part := range strings.Split(field, ",") {
+		// Read the step of the part.
+		step := 1
+		if index := strings.Index(part, "/"); index >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[index+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in cron field %q", field)
+			}
+			part = part[:index]
+		}
+
+		// Read the range of the part: *, a-b, or a single value (up to the maximum with a step).
+		start, end := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = parseValue(bounds[0]); err != nil {
+				return nil, err
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = parseValue(bounds[1]); err != nil {
+					return nil, err
+				}
+			} else if step > 1 {
+				end = max
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid range %q in cron field %q", part, field)
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+// Check if a minute matches the schedule. Like cron, when both the day of month and the day of week are restricted, one of them must match.
+func (schedule *cronSchedule) matches(t time.Time) bool {
+	if !schedule.minutes[t.Minute()] || !schedule.hours[t.Hour()] || !schedule.months[int(t.Month())] {
+		return false
+	}
+	dayMatches := schedule.days[t.Day()]
+	weekdayMatches := schedule.weekdays[int(t.Weekday())]
+	if schedule.daysRestricted && schedule.weekdaysRestricted {
+		return dayMatches || weekdayMatches
+	}
+	return dayMatches && weekdayMatches
+}
+
+// Find the last minute matching the schedule after a time and up to another time, zero when there is none.
+func (schedule *cronSchedule) lastBetween(after time.Time, until time.Time) time.Time {
+	for t := until.Truncate(time.Minute); t.After(after); t = t.Add(-time.Minute) {
+		if schedule.matches(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Compute the period of a relative period name at a time: today, yesterday, last-week (Monday to Sunday), this-month, last-month or last-N-days (the N days before today).
+func getRelativePeriod(period string, t time.Time) (time.Time, time.Time, error) {
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	firstDayOfMonth := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+
+	switch period {
+	case "today":
+		return today, t, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today.Add(-time.Second), nil
+	case "last-week":
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return monday.AddDate(0, 0, -7), monday.Add(-time.Second), nil
+	case "this-month":
+		return firstDayOfMonth, t, nil
+	case "last-month":
+		return firstDayOfMonth.AddDate(0, -1, 0), firstDayOfMonth.Add(-time.Second), nil
+	}
+
+	if strings.HasPrefix(period, "last-") && strings.HasSuffix(period, "-days") {
+		days, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(period, "last-"), "-days"))
+		if err == nil && days > 0 {
+			return today.AddDate(0, 0, -days), today.Add(-time.Second), nil
+		}
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid period %q", period)
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleMatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		time       string
+		want       bool
+	}{
+		{"every minute", "* * * * *", "2023-03-15 10:27", true},
+		{"fixed minute and hour", "30 6 * * *", "2023-03-15 06:30", true},
+		{"fixed minute and hour, other minute", "30 6 * * *", "2023-03-15 06:31", false},
+		{"day of month only", "0 0 1 * *", "2023-03-01 00:00", true},
+		{"day of month only, other day", "0 0 1 * *", "2023-03-02 00:00", false},
+		{"day of week only", "0 0 * * mon", "2023-03-13 00:00", true},
+		{"day of week only, other day", "0 0 * * mon", "2023-03-14 00:00", false},
+		{"day of month or day of week, day of month matches", "0 0 1 * mon", "2023-03-01 00:00", true},
+		{"day of month or day of week, day of week matches", "0 0 1 * mon", "2023-03-13 00:00", true},
+		{"day of month or day of week, none matches", "0 0 1 * mon", "2023-03-14 00:00", false},
+		{"day of month with a star step and day of week, both match", "0 0 */10 * mon", "2023-08-21 00:00", true},
+		{"day of month with a star step and day of week, only day of month matches", "0 0 */10 * mon", "2023-03-11 00:00", false},
+		{"day of month with a star step and day of week, only day of week matches", "0 0 */10 * mon", "2023-03-13 00:00", false},
+		{"sunday as 0", "0 0 * * 0", "2023-03-12 00:00", true},
+		{"sunday as 7", "0 0 * * 7", "2023-03-12 00:00", true},
+		{"sunday as 7, saturday", "0 0 * * 7", "2023-03-11 00:00", false},
+		{"range to sunday as 7", "0 0 * * 5-7", "2023-03-12 00:00", true},
+		{"star step", "*/15 * * * *", "2023-03-15 10:45", true},
+		{"star step, other minute", "*/15 * * * *", "2023-03-15 10:50", false},
+		{"value step up to the maximum", "5/20 * * * *", "2023-03-15 10:45", true},
+		{"value step, before the value", "5/20 * * * *", "2023-03-15 10:00", false},
+		{"range step", "0 8-18/2 * * *", "2023-03-15 18:00", true},
+		{"range step, odd hour", "0 8-18/2 * * *", "2023-03-15 09:00", false},
+		{"list of values", "0 0 * jan,jul *", "2023-07-01 00:00", true},
+		{"list of values, other month", "0 0 * jan,jul *", "2023-03-01 00:00", false},
+		{"shortcut", "@weekly", "2023-03-12 00:00", true},
+		{"shortcut, other day", "@weekly", "2023-03-13 00:00", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := parseCronSchedule(test.expression)
+			if err != nil {
+				t.Fatalf("parseCronSchedule(%q) returned %v", test.expression, err)
+			}
+			date, _ := time.Parse("2006-01-02 15:04", test.time)
+			if got := schedule.matches(date); got != test.want {
+				t.Errorf("%q matches %s = %v, want %v", test.expression, test.time, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCronScheduleInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@every",
+	}
+
+	for _, expression := range tests {
+		if _, err := parseCronSchedule(expression); err == nil {
+			t.Errorf("parseCronSchedule(%q) returned no error", expression)
+		}
+	}
+}
+
+func TestCronScheduleLastBetween(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		after      string
+		until      string
+		want       string
+	}{
+		{"last run in the range", "0 6 * * *", "2023-03-10 12:00", "2023-03-15 10:27", "2023-03-15 06:00"},
+		{"until included", "0 6 * * *", "2023-03-10 12:00", "2023-03-15 06:00", "2023-03-15 06:00"},
+		{"after excluded", "0 6 * * *", "2023-03-15 06:00", "2023-03-15 10:27", ""},
+		{"no run in the range", "0 0 1 * *", "2023-03-02 00:00", "2023-03-15 10:27", ""},
+		{"seconds of until ignored", "27 10 * * *", "2023-03-15 00:00", "2023-03-15 10:27", "2023-03-15 10:27"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := parseCronSchedule(test.expression)
+			if err != nil {
+				t.Fatalf("parseCronSchedule(%q) returned %v", test.expression, err)
+			}
+			after, _ := time.Parse("2006-01-02 15:04", test.after)
+			until, _ := time.Parse("2006-01-02 15:04", test.until)
+			got := schedule.lastBetween(after, until.Add(30*time.Second))
+			if test.want == "" {
+				if !got.IsZero() {
+					t.Errorf("lastBetween = %s, want none", got.Format("2006-01-02 15:04"))
+				}
+				return
+			}
+			if got.Format("2006-01-02 15:04") != test.want {
+				t.Errorf("lastBetween = %s, want %s", got.Format("2006-01-02 15:04"), test.want)
+			}
+		})
+	}
+}
+
+func TestGetRelativePeriod(t *testing.T) {
+	tests := []struct {
+		period    string
+		time      string
+		wantStart string
+		wantEnd   string
+	}{
+		{"today", "2023-03-15 10:27:00", "2023-03-15 00:00:00", "2023-03-15 10:27:00"},
+		{"yesterday", "2023-03-01 10:27:00", "2023-02-28 00:00:00", "2023-02-28 23:59:59"},
+		{"last-week", "2023-03-15 10:27:00", "2023-03-06 00:00:00", "2023-03-12 23:59:59"},
+		{"last-week", "2023-03-13 00:00:00", "2023-03-06 00:00:00", "2023-03-12 23:59:59"},
+		{"last-week", "2023-03-12 23:59:00", "2023-02-27 00:00:00", "2023-03-05 23:59:59"},
+		{"this-month", "2023-03-15 10:27:00", "2023-03-01 00:00:00", "2023-03-15 10:27:00"},
+		{"last-month", "2023-03-15 10:27:00", "2023-02-01 00:00:00", "2023-02-28 23:59:59"},
+		{"last-month", "2023-01-15 10:27:00", "2022-12-01 00:00:00", "2022-12-31 23:59:59"},
+		{"last-7-days", "2023-03-15 10:27:00", "2023-03-08 00:00:00", "2023-03-14 23:59:59"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.period+" at "+test.time, func(t *testing.T) {
+			date, _ := time.Parse("2006-01-02 15:04:05", test.time)
+			start, end, err := getRelativePeriod(test.period, date)
+			if err != nil {
+				t.Fatalf("getRelativePeriod returned %v", err)
+			}
+			if start.Format("2006-01-02 15:04:05") != test.wantStart || end.Format("2006-01-02 15:04:05") != test.wantEnd {
+				t.Errorf("getRelativePeriod = %s, %s, want %s, %s", start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02 15:04:05"), test.wantStart, test.wantEnd)
+			}
+		})
+	}
+
+	for _, period := range []string{"", "tomorrow", "last-0-days", "last-x-days", "last-days"} {
+		if _, _, err := getRelativePeriod(period, time.Now()); err == nil {
+			t.Errorf("getRelativePeriod(%q) returned no error", period)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringP("listen", "l", ":8080", "The address and port of the HTTP server")
+	serveCmd.Flags().IntP("concurrency", "c", 2, "The maximum number of reports running at the same time")
+	serveCmd.Flags().IntP("timeout", "t", 600, "The maximum number of seconds of a report")
+}
+
+// Reports served by the API with the flags exposed as query parameters. The flags of the files and of the incremental exports are
+// never exposed. The reports reading a file (morCallsRateDeckSimulationByDestinations), writing one file per customer
+// (morBillingSummaryByUsersByResellers) or displaying their result in the terminal (lookup) are not served.
+var serveReports = map[string][]string{
+	"morCallsBillingAudit": {"dateStart", "dateEnd", "tolerance"},
+	"morCallsConcurrentPeakPerDaysByDestinationsByProviders":  {"dateStart", "dateEnd", "maxDuration", "compare"},
+	"morCallsDetailRecords":                                   {"dateStart", "dateEnd", "columns", "direction", "disposition", "provider", "device", "number"},
+	"morCallsDurationDistributionByDestinationsByProviders":   {"dateStart", "dateEnd", "buckets", "shortCall", "compare"},
+	"morCallsDurationPerMobileOrLandlinePhones":               {"dateStart", "dateEnd", "compare"},
+	"morCallsErlangBChannelsByProvidersByDeviceGroups":        {"dateStart", "dateEnd", "blocking", "growth", "compare"},
+	"morCallsForecastByProvidersByDeviceGroupsByDestinations": {"dateStart", "dateEnd", "month", "confidence"},
+	"morCallsFraudAlertsByDevices":                            {"dateStart", "dateEnd", "minutes", "baselineDays", "spikeFactor", "minScore"},
+	"morCallsHeatmapPerWeekdaysPerHours":                      {"dateStart", "dateEnd", "direction", "groupBy", "compare"},
+	"morCallsIncomingOutgoingNumbersDurationLastByProvider":   {"dateStart", "dateEnd", "provider", "compare"},
+	"morCallsLeastCostRoutingByDestinationsByProviders":       {"dateStart", "dateEnd", "compare"},
+	"morCallsMarginByUsersByDeviceGroupsByDestinations":       {"dateStart", "dateEnd", "compare"},
+	"morCallsPricesByDestinationsByDeviceGroupsByProviders":   {"dateStart", "dateEnd", "compare"},
+	"morCallsQualityPerDaysByProvidersByDestinations":         {"dateStart", "dateEnd", "compare"},
+	"morIncomingCallsDuration":                                {"dateStart", "dateEnd", "compare"},
+	"morIncomingCallsOriginByDidsByProviders":                 {"dateStart", "dateEnd", "compare"},
+	"morMaxCallsNumberPerDaysByDestinations":                  {"dateStart", "dateEnd", "compare"},
+	"morUnusedDids":                                           {"days", "maxCalls", "lastCallDays"},
+}
+
+// Allowed characters of the string parameters, they are used in the SQL queries of the reports.
+var serveStringParameter = regexp.MustCompile(`^[\w .:,+-]*$`)
+
+// serveReportFlag represents a flag of a report in the reports list of the API.
+type serveReportFlag struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Default string `json:"default"`
+	Usage   string `json:"usage"`
+}
+
+// serveReport represents a report in the reports list of the API.
+type serveReport struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Parameters  []serveReportFlag `json:"parameters"`
+}
+
+// serveExport represents an export returned as JSON by the API.
+type serveExport struct {
+	Report  string              `json:"report"`
+	Columns []string            `json:"columns"`
+	Rows    []map[string]string `json:"rows"`
+}
+
+// reportsServer runs the reports requested on the HTTP API.
+type reportsServer struct {
+	token      string
+	configFile string
+	executable string
+	semaphore  chan struct{}
+	timeout    time.Duration
+}
+
+// Write an error of the API as JSON.
+func writeServeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// Check if a flag of a report is exposed by the API.
+func isServeFlag(report string, flag *pflag.Flag) bool {
+	return slices.Contains(serveReports[report], flag.Name)
+}
+
+// Find the command of a report served by the API, nil when the report is not served.
+func findServeCommand(name string) *cobra.Command {
+	if _, found := serveReports[name]; !found {
+		return nil
+	}
+	for _, command := range rootCmd.Commands() {
+		if command.Name() == name {
+			return command
+		}
+	}
+	return nil
+}
+
+// Validate the query parameters of a report and convert them to the flags of its command.
+func getServeReportArgs(cmd *cobra.Command, query url.Values) ([]string, error) {
+	var args []string
+	for name, values := range query {
+		if name == "format" {
+			continue
+		}
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || !isServeFlag(cmd.Name(), flag) {
+			return nil, fmt.Errorf("unknown parameter %s", name)
+		}
+		if len(values) != 1 {
+			return nil, fmt.Errorf("parameter %s given more than once", name)
+		}
+		value := values[0]
+
+		// Check the value with the type of the flag.
+		var err error
+		switch flag.Value.Type() {
+		case "int":
+			_, err = strconv.Atoi(value)
+		case "float64":
+			_, err = strconv.ParseFloat(value, 64)
+		case "bool":
+			_, err = strconv.ParseBool(value)
+		default:
+			if !serveStringParameter.MatchString(value) {
+				err = fmt.Errorf("unexpected character")
+			} else if strings.HasPrefix(name, "date") {
+				_, err = time.Parse("2006-01-02 15:04:05", value)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter %s: %s", name, value)
+		}
+
+		args = append(args, "--"+name+"="+value)
+	}
+	sort.Strings(args)
+
+	return args, nil
+}
+
+// Check the API token of a request.
+func (server *reportsServer) authorize(w http.ResponseWriter, r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(server.token)) != 1 {
+		writeServeError(w, http.StatusUnauthorized, "invalid API token")
+		return false
+	}
+	return true
+}
+
+// List the reports with their parameters.
+func (server *reportsServer) listReports(w http.ResponseWriter, r *http.Request) {
+	if !server.authorize(w, r) {
+		return
+	}
+
+	var names []string
+	for name := range serveReports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var list []serveReport
+	for _, name := range names {
+		command := findServeCommand(name)
+		if command == nil {
+			continue
+		}
+		report := serveReport{Name: name, Description: command.Short}
+		command.Flags().VisitAll(func(flag *pflag.Flag) {
+			if isServeFlag(name, flag) {
+				report.Parameters = append(report.Parameters, serveReportFlag{Name: flag.Name, Type: flag.Value.Type(), Default: flag.DefValue, Usage: flag.Usage})
+			}
+		})
+		list = append(list, report)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// Run a report with the query parameters and return its export as JSON or as a CSV file.
+func (server *reportsServer) runReport(w http.ResponseWriter, r *http.Request) {
+	if !server.authorize(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeServeError(w, http.StatusMethodNotAllowed, "only GET is allowed")
+		return
+	}
+
+	// Validate the report and its parameters.
+	name := strings.TrimPrefix(r.URL.Path, "/reports/")
+	command := findServeCommand(name)
+	if command == nil {
+		writeServeError(w, http.StatusNotFound, "unknown report "+name)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		writeServeError(w, http.StatusBadRequest, "invalid format "+format+", use json or csv")
+		return
+	}
+	reportArgs, err := getServeReportArgs(command, r.URL.Query())
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Wait for a free slot to limit the reports querying the database at the same time.
+	select {
+	case server.semaphore <- struct{}{}:
+		defer func() { <-server.semaphore }()
+	case <-r.Context().Done():
+		return
+	}
+
+	// Run the report in its own process and working directory, the exports of the reports running at the same time do not collide.
+	workDir, err := os.MkdirTemp("", "mor-report-")
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer os.RemoveAll(workDir)
+
+	ctx, cancel := context.WithTimeout(r.Context(), server.timeout)
+	defer cancel()
+	args := append([]string{name, "--config", server.configFile}, reportArgs...)
+	reportCmd := exec.CommandContext(ctx, server.executable, args...)
+	reportCmd.Dir = workDir
+	output, err := reportCmd.Output()
+	log.Printf("report %s %s run in %s", name, strings.Join(reportArgs, " "), workDir)
+	if err != nil {
+		// The reports log their fatal error as the last line of the error output.
+		message := err.Error()
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			lines := strings.Split(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+			message = lines[len(lines)-1]
+		}
+		writeServeError(w, http.StatusInternalServerError, fmt.Sprintf("report %s failed: %s", name, message))
+		return
+	}
+
+	// Find the export of the report, the comparison when a period is compared.
+	pattern := "*_export.csv"
+	if r.URL.Query().Get("compare") != "" {
+		pattern = "*_compare.csv"
+	}
+	files, _ := filepath.Glob(filepath.Join(workDir, pattern))
+	filename := ""
+	for _, file := range files {
+		if !strings.HasSuffix(file, "_previous_export.csv") {
+			filename = file
+		}
+	}
+	if filename == "" {
+		// The reports print the invalid parameters on the standard output without export.
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		writeServeError(w, http.StatusBadRequest, lines[len(lines)-1])
+		return
+	}
+
+	// Return the export as a CSV file.
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s_%s\"", name, filepath.Base(filename)))
+		http.ServeFile(w, r, filename)
+		return
+	}
+
+	// Return the export as JSON.
+	file, err := os.Open(filename)
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil || len(records) == 0 {
+		writeServeError(w, http.StatusInternalServerError, fmt.Sprintf("unable to read the export of %s: %v", name, err))
+		return
+	}
+	export := serveExport{Report: name, Columns: records[0], Rows: []map[string]string{}}
+	for _, record := range records[1:] {
+		row := make(map[string]string)
+		for i, column := range records[0] {
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+		export.Rows = append(export.Rows, row)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(export)
+}
+
+// Define the main Cobra command for serving the reports on an HTTP API.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the reports on an HTTP API returning JSON or CSV files.",
+	Long: `Serve the reports on an HTTP API: GET /reports lists the reports with their parameters, and GET /reports/[report] runs a report with the query parameters mirroring its flags (e.g., dateStart, dateEnd, provider) and returns its export as JSON or as a CSV file.
+
+Usage:
+  serve [-l [listen]] [-c [concurrency]] [-t [timeout]]
+
+Flags:
+  -l, --listen string      The address and port of the HTTP server (default ":8080")
+  -c, --concurrency int    The maximum number of reports running at the same time (default 2)
+  -t, --timeout int        The maximum number of seconds of a report (default 600)
+
+Example:
+  serve -l 127.0.0.1:8080 -c 1
+
+The requests are authenticated with the API_TOKEN of the configuration file in the Authorization header ('Bearer [token]'). The format query parameter is json (default) or csv, and the compare parameter returns the comparison export. The parameters are validated with the type of the flags and the dates with the 'YYYY-MM-DD HH:mm:SS' format. The requests wait for a free slot when the maximum number of reports are running. Each report is run in its own process with the same configuration file. Only the date and filter flags of the reports are exposed, the reports reading a file, writing one file per customer or displaying their result in the terminal (morCallsRateDeckSimulationByDestinations, morBillingSummaryByUsersByResellers and lookup) are not served.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the listen address, the concurrency and the timeout from the command-line flags.
+		listen, _ := cmd.Flags().GetString("listen")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		timeout, _ := cmd.Flags().GetInt("timeout")
+		if concurrency <= 0 || timeout <= 0 {
+			fmt.Println("Invalid concurrency or timeout. Please use a number greater than 0")
+			return
+		}
+
+		// The API token is required.
+		token := viper.GetString("API_TOKEN")
+		if token == "" {
+			fmt.Println("API_TOKEN is missing in the configuration file")
+			return
+		}
+
+		// Retrieve the executable and the configuration file to run the reports.
+		executable, err := os.Executable()
+		if err != nil {
+			log.Fatal(err)
+		}
+		configFile, err := filepath.Abs(viper.ConfigFileUsed())
+		if err != nil {
+			log.Fatal(err)
+		}
+		server := &reportsServer{token: token, configFile: configFile, executable: executable, semaphore: make(chan struct{}, concurrency), timeout: time.Duration(timeout) * time.Second}
+
+		// Display the server information for the user's reference.
+		fmt.Printf("serve called with listen: %s, concurrency: %d and timeout: %d\n", listen, concurrency, timeout)
+
+		// Serve the API.
+		mux := http.NewServeMux()
+		mux.HandleFunc("/reports", server.listReports)
+		mux.HandleFunc("/reports/", server.runReport)
+		log.Fatal(http.ListenAndServe(listen, mux))
+	},
+}
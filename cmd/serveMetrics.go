@@ -0,0 +1,351 @@
+package cmd
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Initialize the command.
+func init() {
+	rootCmd.AddCommand(serveMetricsCmd)
+	serveMetricsCmd.Flags().StringP("listen", "l", ":9150", "The address and port of the HTTP server")
+	serveMetricsCmd.Flags().IntP("interval", "i", 60, "The number of seconds between two queries of the MOR database")
+}
+
+// ModelMorMetricsCalls represents the new outgoing calls of a provider, a device group and a destination.
+type ModelMorMetricsCalls struct {
+	Provider    string
+	DeviceGroup string
+	Destination string
+	Prefix      string
+	Calls       int
+	Answered    int
+	Billsec     int
+	Cost        float64
+	LastID      int64
+}
+
+// ModelMorMetricsGauge represents a value of a gauge, by label.
+type ModelMorMetricsGauge struct {
+	Label string
+	Value int
+}
+
+// Retrieve the new calls from the database and return them as a slice of models.
+func getModelMorMetricsCalls(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorMetricsCalls
+
+		if err := rows.Scan(
+			&msg.Provider,
+			&msg.DeviceGroup,
+			&msg.Destination,
+			&msg.Prefix,
+			&msg.Calls,
+			&msg.Answered,
+			&msg.Billsec,
+			&msg.Cost,
+			&msg.LastID,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Retrieve the values of a gauge from the database and return them as a slice of models.
+func getModelMorMetricsGauge(stmt *sql.Stmt) ([]any, error) {
+	var messages []any
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ModelMorMetricsGauge
+
+		if err := rows.Scan(
+			&msg.Label,
+			&msg.Value,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// metricsFamily represents a Prometheus metric with its values by label value.
+type metricsFamily struct {
+	name       string
+	help       string
+	metricType string
+	label      string
+	values     map[string]float64
+}
+
+// Write the metric in the Prometheus text format.
+func (family *metricsFamily) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", family.name, family.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", family.name, family.metricType)
+
+	var labelValues []string
+	for labelValue := range family.values {
+		labelValues = append(labelValues, labelValue)
+	}
+	sort.Strings(labelValues)
+
+	for _, labelValue := range labelValues {
+		value := strconv.FormatFloat(family.values[labelValue], 'g', -1, 64)
+		if family.label == "" {
+			fmt.Fprintf(w, "%s %s\n", family.name, value)
+			continue
+		}
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %s\n", family.name, family.label, escapeMetricsLabel(labelValue), value)
+	}
+}
+
+// Escape a label value of the Prometheus text format.
+func escapeMetricsLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// morMetrics holds the metrics of the MOR database and their last rendering served to the scrapes.
+type morMetrics struct {
+	families   []*metricsFamily
+	byName     map[string]*metricsFamily
+	lastCallID int64
+	mutex      sync.RWMutex
+	rendered   []byte
+}
+
+// Create the metrics with their families.
+func newMorMetrics() *morMetrics {
+	metrics := &morMetrics{byName: make(map[string]*metricsFamily), lastCallID: -1}
+
+	// Add the counters of the calls by provider, country and device group.
+	for _, dimension := range []string{"provider", "country", "device_group"} {
+		title := strings.ReplaceAll(dimension, "_", " ")
+		metrics.addFamily("mor_"+dimension+"_calls_total", "Outgoing calls by "+title+".", "counter", dimension)
+		metrics.addFamily("mor_"+dimension+"_answered_calls_total", "Answered outgoing calls by "+title+".", "counter", dimension)
+		metrics.addFamily("mor_"+dimension+"_billed_seconds_total", "Billed seconds of the outgoing calls by "+title+".", "counter", dimension)
+		metrics.addFamily("mor_"+dimension+"_cost_total", "Provider cost of the outgoing calls by "+title+".", "counter", dimension)
+	}
+
+	// Add the gauges of the current state.
+	metrics.addFamily("mor_active_dids", "Active DIDs.", "gauge", "")
+	metrics.addFamily("mor_concurrent_calls", "Current concurrent calls by provider.", "gauge", "provider")
+	metrics.addFamily("mor_up", "Whether the last query of the MOR database succeeded.", "gauge", "")
+	metrics.addFamily("mor_last_refresh_timestamp_seconds", "Unix time of the last successful query of the MOR database.", "gauge", "")
+	metrics.addFamily("mor_refresh_duration_seconds", "Duration of the last query of the MOR database.", "gauge", "")
+
+	return metrics
+}
+
+// Add a metric family to the metrics.
+func (metrics *morMetrics) addFamily(name string, help string, metricType string, label string) {
+	family := &metricsFamily{name: name, help: help, metricType: metricType, label: label, values: make(map[string]float64)}
+	metrics.families = append(metrics.families, family)
+	metrics.byName[name] = family
+}
+
+// Query the MOR database, update the metrics and render them for the next scrapes.
+func (metrics *morMetrics) refresh() {
+	start := time.Now()
+	err := MorSession(metrics.update)
+	if err != nil {
+		log.Println(err)
+		metrics.byName["mor_up"].values[""] = 0
+	} else {
+		metrics.byName["mor_up"].values[""] = 1
+		metrics.byName["mor_last_refresh_timestamp_seconds"].values[""] = float64(time.Now().Unix())
+	}
+	metrics.byName["mor_refresh_duration_seconds"].values[""] = time.Since(start).Seconds()
+
+	// Render the metrics in the Prometheus text format.
+	var buffer bytes.Buffer
+	for _, family := range metrics.families {
+		family.write(&buffer)
+	}
+
+	metrics.mutex.Lock()
+	metrics.rendered = buffer.Bytes()
+	metrics.mutex.Unlock()
+}
+
+// Query the MOR database on a single connection and update the counters and the gauges.
+func (metrics *morMetrics) update(db *Db) error {
+	// Start the counters from the last call at the first query, the counters are added from the calls ID to count the calls inserted late.
+	if metrics.lastCallID < 0 {
+		_, err := db.request("SELECT IFNULL(MAX(id), 0) FROM mor.calls;", func(stmt *sql.Stmt) ([]any, error) {
+			return nil, stmt.QueryRow().Scan(&metrics.lastCallID)
+		})
+		if err != nil {
+			metrics.lastCallID = -1
+			return err
+		}
+	}
+
+	// Build the device group SQL filter.
+	srcDevicesIDFilter, _ := getDeviceGroupFilter("c.src_device_id")
+
+	// Construct the SQL query of the new outgoing calls.
+	request := fmt.Sprintf(`SELECT
+			p.name AS Provider,
+			%s AS DeviceGroup,
+			IFNULL(d.name, '') AS Destination,
+			c.prefix AS Prefix,
+			COUNT(*) AS Calls,
+			SUM(CASE WHEN c.disposition = 'ANSWERED' THEN 1 ELSE 0 END) AS Answered,
+			SUM(c.billsec) AS Billsec,
+			IFNULL(SUM(c.provider_price), 0) AS Cost,
+			MAX(c.id) AS LastID
+		FROM mor.calls c
+		INNER JOIN mor.providers p ON c.provider_id = p.id
+		LEFT JOIN mor.destinations d ON c.prefix = d.prefix
+		WHERE
+			c.id > %d AND
+			c.provider_id > 0
+		GROUP BY Provider, DeviceGroup, Destination, Prefix;`, srcDevicesIDFilter, metrics.lastCallID)
+
+	// Send the SQL request and add the new calls to the counters.
+	results, err := db.request(request, getModelMorMetricsCalls)
+	if err != nil {
+		return err
+	}
+	countriesRegion := make(map[string]string)
+	for _, result := range results {
+		oneResult := result.(ModelMorMetricsCalls)
+		country, found := countriesRegion[oneResult.Prefix]
+		if !found {
+			_, country = getCountryFromPrefix(oneResult.Prefix, oneResult.Destination)
+			countriesRegion[oneResult.Prefix] = country
+		}
+		deviceGroup := oneResult.DeviceGroup
+		if deviceGroup == "" {
+			deviceGroup = "other"
+		}
+		for dimension, labelValue := range map[string]string{"provider": oneResult.Provider, "country": country, "device_group": deviceGroup} {
+			metrics.byName["mor_"+dimension+"_calls_total"].values[labelValue] += float64(oneResult.Calls)
+			metrics.byName["mor_"+dimension+"_answered_calls_total"].values[labelValue] += float64(oneResult.Answered)
+			metrics.byName["mor_"+dimension+"_billed_seconds_total"].values[labelValue] += float64(oneResult.Billsec)
+			metrics.byName["mor_"+dimension+"_cost_total"].values[labelValue] += oneResult.Cost
+		}
+		if oneResult.LastID > metrics.lastCallID {
+			metrics.lastCallID = oneResult.LastID
+		}
+	}
+
+	// Send the SQL requests of the gauges.
+	results, err = db.request("SELECT '' AS Label, COUNT(*) AS Value FROM mor.dids WHERE status = 'active';", getModelMorMetricsGauge)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		metrics.byName["mor_active_dids"].values[""] = float64(result.(ModelMorMetricsGauge).Value)
+	}
+
+	results, err = db.request(`SELECT
+			IFNULL(p.name, '') AS Label,
+			COUNT(*) AS Value
+		FROM mor.activecalls a
+		LEFT JOIN mor.providers p ON a.provider_id = p.id
+		GROUP BY Label;`, getModelMorMetricsGauge)
+	if err != nil {
+		return err
+	}
+	concurrentCalls := metrics.byName["mor_concurrent_calls"].values
+	for provider := range concurrentCalls {
+		concurrentCalls[provider] = 0
+	}
+	for _, result := range results {
+		oneResult := result.(ModelMorMetricsGauge)
+		concurrentCalls[oneResult.Label] = float64(oneResult.Value)
+	}
+
+	return nil
+}
+
+// Serve the last rendering of the metrics.
+func (metrics *morMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	metrics.mutex.RLock()
+	defer metrics.mutex.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(metrics.rendered)
+}
+
+// Define the main Cobra command for serving the Prometheus metrics.
+var serveMetricsCmd = &cobra.Command{
+	Use:   "serve-metrics",
+	Short: "Serve the MOR calls metrics on an HTTP endpoint for Prometheus.",
+	Long: `Query the MOR database periodically and serve the calls, answered calls, billed seconds and cost counters by provider, country and device group, the active DIDs and the current concurrent calls in the Prometheus text format on the /metrics endpoint.
+
+Usage:
+  serve-metrics [-l [listen]] [-i [interval]]
+
+Flags:
+  -l, --listen string    The address and port of the HTTP server (default ":9150")
+  -i, --interval int     The number of seconds between two queries of the MOR database (default 60)
+
+Example:
+  serve-metrics -l 127.0.0.1:9150 -i 120
+
+The MOR database is only queried every interval, whatever the number of scrapes: the scrapes are served the metrics of the last query. The counters count the outgoing calls inserted in the MOR database since the start of the command, from the calls ID, and the cost is the provider price. The mor_up gauge is 0 when the last query failed, the metrics of the previous query are still served.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Obtain the listen address and the interval from the command-line flags.
+		listen, _ := cmd.Flags().GetString("listen")
+		interval, _ := cmd.Flags().GetInt("interval")
+		if interval <= 0 {
+			fmt.Println("Invalid interval. Please use a number of seconds greater than 0")
+			return
+		}
+
+		// Display the server information for the user's reference.
+		fmt.Printf("serve-metrics called with listen: %s and interval: %d\n", listen, interval)
+
+		// Query the MOR database a first time, then every interval.
+		metrics := newMorMetrics()
+		metrics.refresh()
+		go func() {
+			ticker := time.NewTicker(time.Duration(interval) * time.Second)
+			defer ticker.Stop()
+			for range ticker.C {
+				metrics.refresh()
+			}
+		}()
+
+		// Serve the metrics.
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+		log.Fatal(http.ListenAndServe(listen, mux))
+	},
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestServeReports(t *testing.T) {
+	for name, flags := range serveReports {
+		command := findServeCommand(name)
+		if command == nil {
+			t.Errorf("served report %s is not a command", name)
+			continue
+		}
+		for _, flag := range flags {
+			if command.Flags().Lookup(flag) == nil {
+				t.Errorf("served flag %s is not a flag of %s", flag, name)
+			}
+		}
+	}
+}
+
+func TestGetServeReportArgs(t *testing.T) {
+	command := findServeCommand("morCallsQualityPerDaysByProvidersByDestinations")
+
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{"dates and format", "dateStart=2023-01-01+00:00:00&dateEnd=2023-01-31+23:59:59&format=csv", []string{"--dateEnd=2023-01-31 23:59:59", "--dateStart=2023-01-01 00:00:00"}, false},
+		{"compare", "compare=previous", []string{"--compare=previous"}, false},
+		{"invalid date", "dateStart=2023-01-01", nil, true},
+		{"unexpected character", "compare=previous%27", nil, true},
+		{"repeated parameter", "compare=previous&compare=year-ago", nil, true},
+		{"unknown parameter", "provider=sfr", nil, true},
+		{"incremental flag", "incremental=true", nil, true},
+		{"state file flag", "stateFile=.env", nil, true},
+		{"lookback flag", "lookback=3", nil, true},
+		{"help flag", "help=true", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(test.query)
+			got, err := getServeReportArgs(command, query)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("getServeReportArgs error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("getServeReportArgs = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
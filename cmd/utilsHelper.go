@@ -2,9 +2,16 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+	"github.com/pariz/gountries"
 )
 
+// Query object for the gountries library, which is used to fetch additional country information.
+var countriesQuery = gountries.New()
+
 // Format the duration in minutes as "X h Y m".
 func formatTimeMinutesToHours(minutes int) string {
 	hours := minutes / 60
@@ -13,9 +20,9 @@ func formatTimeMinutesToHours(minutes int) string {
 }
 
 // Format the duration in seconds as "X h Y m".
-func formatTimeSecondsToHours(minutes int) string {
-	hours := minutes / 3600
-	minutes = minutes % 3600
+func formatTimeSecondsToHours(seconds int) string {
+	hours := seconds / 3600
+	minutes := (seconds % 3600) / 60
 	return fmt.Sprintf("%d h %d m", hours, minutes)
 }
 
@@ -28,3 +35,149 @@ func removeZero(number string) string {
 	}
 	return number
 }
+
+// Normalize a phone number in E.164 format and retrieve its region code and its type.
+func getNumberInformation(number string) (string, string, phonenumbers.PhoneNumberType, error) {
+	// Keep only the digits of the number.
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+
+	// Remove the international and the national prefixes if the number is not already international.
+	if !strings.HasPrefix(strings.TrimSpace(number), "+") {
+		if strings.HasPrefix(digits, "00") && !strings.HasPrefix(digits, "000") {
+			digits = digits[2:]
+		} else {
+			digits = removeZero(digits)
+		}
+	}
+
+	// Parse and format phone number information.
+	phoneNumber, err := phonenumbers.Parse("+"+digits, "")
+	if err != nil {
+		return "", "", phonenumbers.UNKNOWN, err
+	}
+
+	return phonenumbers.Format(phoneNumber, phonenumbers.E164), phonenumbers.GetRegionCodeForNumber(phoneNumber), phonenumbers.GetNumberType(phoneNumber), nil
+}
+
+// Retrieve the name of a phone number type.
+func getNumberTypeName(numberType phonenumbers.PhoneNumberType) string {
+	switch numberType {
+	case phonenumbers.FIXED_LINE:
+		return "FIXED_LINE"
+	case phonenumbers.MOBILE:
+		return "MOBILE"
+	case phonenumbers.FIXED_LINE_OR_MOBILE:
+		return "FIXED_LINE_OR_MOBILE"
+	case phonenumbers.TOLL_FREE:
+		return "TOLL_FREE"
+	case phonenumbers.PREMIUM_RATE:
+		return "PREMIUM_RATE"
+	case phonenumbers.SHARED_COST:
+		return "SHARED_COST"
+	case phonenumbers.VOIP:
+		return "VOIP"
+	case phonenumbers.PERSONAL_NUMBER:
+		return "PERSONAL_NUMBER"
+	case phonenumbers.PAGER:
+		return "PAGER"
+	case phonenumbers.UAN:
+		return "UAN"
+	case phonenumbers.VOICEMAIL:
+		return "VOICEMAIL"
+	}
+	return "UNKNOWN"
+}
+
+// Retrieve the name of the country of a region code.
+func getCountryName(regionCode string) string {
+	country, err := countriesQuery.FindCountryByAlpha(regionCode)
+	if err != nil || country.Name.Common == "" {
+		return "UNKNOWN"
+	}
+	return country.Name.Common
+}
+
+// Retrieve the formatted prefix and the country of a MOR destination prefix.
+func getCountryFromPrefix(prefix string, destination string) (string, string) {
+	// Process and format the prefix for phone numbers.
+	prefix = "+" + prefix
+	prefix = strings.TrimRight(prefix+"000000", " ")[0:6]
+
+	// Parse and format phone number information.
+	phoneNumber, err := phonenumbers.Parse(prefix, "")
+	if err != nil {
+		// Handle the case where phone number information cannot be parsed.
+		return "UNKNOWN", "UNKNOWN"
+	}
+
+	// Format the phone number in international format using the phonenumbers library.
+	formattedPrefix := phonenumbers.Format(phoneNumber, phonenumbers.INTERNATIONAL)
+
+	// Remove the + to the formatted phone prefix.
+	formattedPrefix = strings.Replace(formattedPrefix, "+", "", 1)
+
+	// Retrieve the region (country) code associated with the phone number.
+	regionCode := phonenumbers.GetRegionCodeForNumber(phoneNumber)
+
+	// Find the country information based on the region code.
+	displayRegionQuery, _ := countriesQuery.FindCountryByAlpha(regionCode)
+	displayRegion := displayRegionQuery.Name.Common
+
+	if displayRegion == "" {
+		// If the region is not found, provide fallback information for certain countries.
+		displayRegion = getCountryFromDestinationName(destination)
+	}
+
+	if displayRegion == "" {
+		displayRegion = "UNKNOWN"
+	}
+
+	return formattedPrefix, displayRegion
+}
+
+// Provide fallback country information for certain MOR destination names.
+func getCountryFromDestinationName(destination string) string {
+	destination = strings.ToLower(destination)
+
+	if strings.Contains(destination, "australia") || strings.Contains(destination, "australie") {
+		return "Australia"
+	} else if strings.Contains(destination, "canada") {
+		return "Canada"
+	} else if strings.Contains(destination, "italy") {
+		return "Italy"
+	} else if strings.Contains(destination, "russia") {
+		return "Russia"
+	} else if strings.Contains(destination, "unites states") {
+		return "United States"
+	} else if strings.Contains(destination, "guadeloupe") {
+		return "France"
+	} else if strings.Contains(destination, "morocco") {
+		return "Morocco"
+	} else if strings.Contains(destination, "reunion") || strings.Contains(destination, "r?union") || strings.Contains(destination, "france") {
+		return "France"
+	} else if strings.Contains(destination, "uk") || strings.Contains(destination, "united kingdom") {
+		return "United Kingdom"
+	}
+
+	return ""
+}
+
+// Format a price with two decimals and a decimal comma.
+func formatPrice(price float64) string {
+	return strings.Replace(strconv.FormatFloat(price, 'f', 2, 64), ".", ",", 1)
+}
+
+// Replace the characters which are not allowed in a filename by an underscore.
+func sanitizeFilename(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import "testing"
+
+func TestFormatTimeSecondsToHours(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{0, "0 h 0 m"},
+		{59, "0 h 0 m"},
+		{60, "0 h 1 m"},
+		{3599, "0 h 59 m"},
+		{3600, "1 h 0 m"},
+		{3660, "1 h 1 m"},
+		{90000, "25 h 0 m"},
+	}
+
+	for _, test := range tests {
+		if got := formatTimeSecondsToHours(test.seconds); got != test.want {
+			t.Errorf("formatTimeSecondsToHours(%d) = %s, want %s", test.seconds, got, test.want)
+		}
+	}
+}